@@ -1,13 +1,19 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"log/slog"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"slices"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
 
 	i "github.com/clemthi/runsyncapps/internal"
@@ -17,13 +23,51 @@ import (
 const (
 	traceFile         string = "trace.log"
 	defaultConfigFile string = "config.json"
+
+	configPollInterval = 2 * time.Second
 )
 
+// configFiles is the list of config files given with repeated --config flags
+type configFiles []string
+
+func (c *configFiles) String() string {
+	return strings.Join(*c, ", ")
+}
+
+func (c *configFiles) Set(value string) error {
+	*c = append(*c, value)
+	return nil
+}
+
 func main() {
-	configFile := flag.String("config", defaultConfigFile, "path to a configuration file")
+	var configFiles configFiles
+	flag.Var(&configFiles, "config", "path to a configuration file, can be repeated to merge several files in order (config.json by default)")
+	configFormat := flag.String("config-format", "", "format of the configuration files: json, yaml or toml (guessed from the file extension by default)")
+	profile := flag.String("profile", "", "name of the configuration profile to use (the default profile of the configuration by default)")
+	printConfig := flag.Bool("print-effective-config", false, "print the merged configuration and exit")
 	enableLog := flag.Bool("log", false, "enable logging")
 	flag.Parse()
 
+	if len(configFiles) == 0 {
+		configFiles = append(configFiles, defaultConfigFile)
+	}
+
+	// "runsyncapps schema" prints the JSON Schema of the config file
+	if flag.Arg(0) == "schema" {
+		os.Stdout.Write(i.Schema())
+		return
+	}
+
+	if *printConfig {
+		config, err := i.EffectiveConfig(configFiles, *configFormat, *profile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", err)
+			os.Exit(i.ExitCode(err))
+		}
+		fmt.Println(string(config))
+		return
+	}
+
 	// Init log handler
 	var logHandler slog.Handler
 	if *enableLog {
@@ -35,38 +79,292 @@ func main() {
 		logHandler = slog.NewTextHandler(io.Discard, nil)
 	}
 
-	// Init systray icon
-	go systray.Run(i.OnReadyUI, func() { os.Exit(0) })
+	// The first signal stops the apps, the second one kills them right away
+	ctx, cancel := context.WithCancelCause(context.Background())
+	forceCtx, forceCancel := context.WithCancel(context.Background())
+	defer forceCancel()
+	sigs := make(chan os.Signal, 2)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		sig := <-sigs
+		cancel(fmt.Errorf("received signal %s", sig))
+		<-sigs
+		forceCancel()
+	}()
 
-	if err := run(*configFile, logHandler); err != nil {
+	if err := run(ctx, forceCtx, cancel, configFiles, *configFormat, *profile, logHandler); err != nil {
 		fmt.Fprintf(os.Stderr, "%s\n", err)
-		os.Exit(1)
+		os.Exit(i.ExitCode(err))
 	}
 }
 
-func run(configFile string, logHandler slog.Handler) error {
+// errProfileSwitch is the cause of the stop of the apps when the user switches the profile
+var errProfileSwitch = errors.New("profile switch")
+
+// errGroupRemoved is the cause of the stop of the apps of a group removed from the reloaded config
+var errGroupRemoved = errors.New("group removed from the config")
+
+// run loads the config and supervises its groups until all of them are over,
+// the systray menu lists the groups and switches the profile: the apps of the
+// current profile are stopped and the ones of the new profile are started.
+// The config is reloaded when its files change, on SIGHUP or from the systray.
+func run(ctx context.Context, forceCtx context.Context, cancel context.CancelCauseFunc, configFiles []string, configFormat string, profile string, logHandler slog.Handler) error {
 	logger := slog.New(logHandler)
 
-	config, err := i.LoadConfigFile(configFile)
+	config, err := i.LoadConfigFiles(configFiles, configFormat, profile)
 	if err != nil {
 		logger.Error("cannot load config file", "error", err)
 		return err
 	}
+	if profile == "" {
+		profile = config.DefaultProfile
+	}
 
-	p := i.NewProcessHander(logger)
-	runningProcs, err := p.StartProcesses(config.Applications)
-	if err != nil {
+	// Init systray icon, quitting from the tray leaves the apps running
+	ui := i.NewTrayUI(config.ProfileNames(), profile)
+	go systray.Run(ui.OnReady, func() { cancel(i.ErrQuit) })
+
+	hangups := make(chan os.Signal, 1)
+	signal.Notify(hangups, syscall.SIGHUP)
+	defer signal.Stop(hangups)
+	watcher := i.NewConfigWatcher(config.Files())
+	ticker := time.NewTicker(configPollInterval)
+	defer ticker.Stop()
+
+	for {
+		runCtx, stopRun := context.WithCancelCause(ctx)
+		done := make(chan error, 1)
+		updates := make(chan *i.ConfigFile, 1)
+		go func(config *i.ConfigFile) {
+			done <- runGroups(runCtx, forceCtx, config, ui, updates, logger)
+		}(config)
+
+		// reload loads the config again and hands it to the running groups,
+		// the current config is kept when the new one is invalid
+		reload := func(cause string) {
+			logger.Info("reloading config", "cause", cause)
+			newConfig, err := i.LoadConfigFiles(configFiles, configFormat, profile)
+			if err != nil {
+				logger.Error("cannot reload config file", "error", err)
+				return
+			}
+			config = newConfig
+			watcher = i.NewConfigWatcher(config.Files())
+			select {
+			case <-updates:
+			default:
+			}
+			updates <- config
+		}
+
+	waitSwitch:
+		for {
+			select {
+			case err := <-done:
+				stopRun(nil)
+				return err
+			case <-ui.Reloads():
+				reload("systray")
+			case sig := <-hangups:
+				reload(fmt.Sprintf("received signal %s", sig))
+			case <-ticker.C:
+				if watcher.Changed() {
+					reload("config file changed")
+				}
+			case newProfile := <-ui.ProfileSwitches():
+				if newProfile == profile {
+					continue
+				}
+				newConfig, err := i.LoadConfigFiles(configFiles, configFormat, newProfile)
+				if err != nil {
+					logger.Error("cannot load profile", "profile", newProfile, "error", err)
+					ui.SetProfile(profile)
+					continue
+				}
+				logger.Info("switching profile", "from", profile, "to", newProfile)
+				stopRun(errProfileSwitch)
+				<-done
+				config, profile = newConfig, newProfile
+				watcher = i.NewConfigWatcher(config.Files())
+				ui.SetProfile(profile)
+				break waitSwitch
+			}
+		}
+	}
+}
+
+// groupRunner supervises a group, its config is replaced when the config is reloaded
+type groupRunner struct {
+	name   string
+	p      *i.ProcessHander
+	logger *slog.Logger
+	stop   context.CancelCauseFunc
+
+	mu    sync.Mutex
+	mode  string
+	group i.GroupConfig
+}
+
+func (r *groupRunner) config() (string, i.GroupConfig) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.mode, r.group
+}
+
+// update replaces the config of the group, the running apps are updated right away
+func (r *groupRunner) update(mode string, group i.GroupConfig) {
+	r.mu.Lock()
+	r.mode, r.group = mode, group
+	r.mu.Unlock()
+	r.p.UpdateApps(group.Applications)
+}
+
+type groupResult struct {
+	runner *groupRunner
+	err    error
+}
+
+// runGroups supervises the groups of the config concurrently until all of
+// them are over. When the config is reloaded, the groups are updated in
+// place, the new groups are started and the removed ones are stopped.
+func runGroups(ctx context.Context, forceCtx context.Context, config *i.ConfigFile, ui *i.TrayUI, updates <-chan *i.ConfigFile, logger *slog.Logger) error {
+	runners := make(map[string]*groupRunner) // by name, including the groups which are over
+	var active []*groupRunner                // listed in the systray
+	results := make(chan groupResult)
+	pacer := i.NewLaunchPacer(config.StaggerInterval, config.MaxParallelStarts)
+
+	setTrayGroups := func() {
+		trayGroups := make([]i.TrayGroup, len(active))
+		for index, r := range active {
+			trayGroups[index] = i.TrayGroup{Name: r.name, State: r.p.State}
+		}
+		ui.SetGroups(trayGroups)
+	}
+
+	apply := func(config *i.ConfigFile, reloaded bool) {
+		if reloaded {
+			pacer.Configure(config.StaggerInterval, config.MaxParallelStarts)
+		}
+		groups := config.AppGroups()
+		names := make(map[string]bool, len(groups))
+		for index, group := range groups {
+			name := group.DisplayName(index)
+			names[name] = true
+			if r, found := runners[name]; found {
+				r.update(config.Mode, group)
+				continue
+			}
+			if reloaded {
+				logger.Info("group added", "group", name)
+			}
+
+			groupLogger := logger
+			if len(groups) > 1 {
+				groupLogger = logger.With("group", name)
+			}
+			groupCtx, stop := context.WithCancelCause(ctx)
+			r := &groupRunner{
+				name:   name,
+				p:      i.NewProcessHander(groupLogger, filepath.Dir(traceFile), pacer),
+				logger: groupLogger,
+				stop:   stop,
+				mode:   config.Mode,
+				group:  group,
+			}
+			runners[name] = r
+			active = append(active, r)
+			go func() {
+				results <- groupResult{r, runGroup(groupCtx, forceCtx, r)}
+			}()
+		}
+		for name, r := range runners {
+			if !names[name] {
+				logger.Info("group removed", "group", name)
+				r.stop(errGroupRemoved)
+				delete(runners, name)
+			}
+		}
+		setTrayGroups()
+	}
+
+	apply(config, false)
+	var errs []error
+	for len(active) > 0 {
+		select {
+		case result := <-results:
+			result.runner.stop(nil)
+			active = slices.DeleteFunc(active, func(r *groupRunner) bool { return r == result.runner })
+			errs = append(errs, result.err)
+			setTrayGroups()
+		case config := <-updates:
+			apply(config, true)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// runGroup starts the apps of the group and monitors them until the group
+// trigger fires or ctx is cancelled, the apps are then stopped unless the user
+// quit from the systray. In supervise mode, the apps are relaunched until ctx
+// is cancelled, with a growing delay after consecutive early failures.
+// Cancelling forceCtx kills the apps being stopped right away.
+func runGroup(ctx context.Context, forceCtx context.Context, r *groupRunner) error {
+	failures := 0
+	for {
+		_, group := r.config()
+		begin := time.Now()
+		err := runCycle(ctx, forceCtx, r.p, group, r.logger)
+		mode, group := r.config()
+		if mode != i.ModeSupervise || ctx.Err() != nil {
+			return err
+		}
+
+		// back off the relaunches while the apps keep failing to start or crashing right away
+		if !i.FailedEarly(err, time.Since(begin)) {
+			failures = 0
+		} else {
+			failures++
+			delay := i.RelaunchDelay(failures)
+			r.logger.Warn("apps failed early, delaying the relaunch", "delay", delay.String(), "failures", failures)
+			select {
+			case <-time.After(delay):
+			case <-ctx.Done():
+				r.logger.Info("stop requested", "cause", context.Cause(ctx))
+				return nil
+			}
+		}
+		if err := r.p.WaitRelaunch(ctx, group.Applications); err != nil {
+			r.logger.Info("stop requested", "cause", context.Cause(ctx))
+			return nil
+		}
+	}
+}
+
+// runCycle starts the apps, monitors them and stops them
+func runCycle(ctx context.Context, forceCtx context.Context, p *i.ProcessHander, group i.GroupConfig, logger *slog.Logger) error {
+	err := p.StartProcesses(ctx, group.Applications, time.Duration(group.WaitCheck))
+	if err != nil && ctx.Err() == nil {
 		logger.Error("cannot start app", "error", err)
+		p.KillProcesses(forceCtx, 0)
 		return fmt.Errorf("error launching apps : %w", err)
 	}
 
-	time.Sleep(time.Duration(config.WaitCheck) * time.Second)
-	p.CheckRunningProcesses(runningProcs)
+	var exitErr error
+	if ctx.Err() == nil {
+		exitErr = p.CheckRunningProcesses(ctx, group.Trigger)
+		if exitErr != nil {
+			logger.Error("app failed", "error", exitErr)
+		}
+	}
 
-	time.Sleep(time.Duration(config.WaitExit) * time.Second)
-	p.KillProcesses(runningProcs)
+	if errors.Is(context.Cause(ctx), i.ErrQuit) {
+		logger.Info("quit from systray, leaving apps running")
+		return nil
+	}
+	p.KillProcesses(forceCtx, time.Duration(group.WaitExit))
 
-	return nil
+	return exitErr
 }
 
 func addTimeSuffix(filePath string) string {
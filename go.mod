@@ -3,8 +3,11 @@ module github.com/clemthi/runsyncapps
 go 1.22
 
 require (
+	github.com/BurntSushi/toml v1.6.0
 	github.com/getlantern/systray v1.2.2
 	github.com/keybase/go-ps v0.0.0-20190827175125-91aafc93ba19
+	golang.org/x/sys v0.1.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -17,5 +20,4 @@ require (
 	github.com/go-stack/stack v1.8.0 // indirect
 	github.com/oxtoacart/bpool v0.0.0-20190530202638-03653db5a59c // indirect
 	github.com/stretchr/testify v1.9.0 // indirect
-	golang.org/x/sys v0.1.0 // indirect
 )
@@ -2,29 +2,118 @@ package internal
 
 import (
 	"encoding/json"
-	"os"
 )
 
 type AppConfig struct {
-	Path                string `json:"path"`
-	UseExistingInstance bool   `json:"useExistingInstance"`
-	KillOnExit          bool   `json:"killOnExit"`
+	Name                string            `json:"name"`
+	Disabled            bool              `json:"disabled"`
+	DependsOn           []string          `json:"dependsOn"`
+	Path                string            `json:"path"`
+	Args                []string          `json:"args"`
+	Env                 map[string]string `json:"env"`
+	ReplaceEnv          bool              `json:"replaceEnv"`
+	EnvFile             string            `json:"envFile"`
+	WorkingDir          string            `json:"workingDir"`
+	UseExistingInstance bool              `json:"useExistingInstance"`
+	Match               *MatchConfig      `json:"match"`
+	Adopt               string            `json:"adopt"`
+	ClosedWhen          string            `json:"closedWhen"`
+	KillOnExit          bool              `json:"killOnExit"`
+	StopSignal          string            `json:"stopSignal"`
+	StopTimeout         Duration          `json:"stopTimeout"`
+	Restart             string            `json:"restart"`
+	MaxRetries          int               `json:"maxRetries"`
+	RestartDelay        Duration          `json:"restartDelay"`
+	RestartMaxDelay     Duration          `json:"restartMaxDelay"`
+	RestartWindow       Duration          `json:"restartWindow"`
+	StartDelay          Duration          `json:"startDelay"`
+	Critical            *bool             `json:"critical"`
+	OnExit              []ExitRule        `json:"onExit"`
+	Readiness           *ProbeConfig      `json:"readiness"`
+	Liveness            *ProbeConfig      `json:"liveness"`
+	Stdout              string            `json:"stdout"`
+	Stderr              string            `json:"stderr"`
+	LogMaxSize          int               `json:"logMaxSize"`
+	LogMaxFiles         int               `json:"logMaxFiles"`
+}
+
+// IsCritical tells if the exit of the app stops the group, apps are critical by default
+func (a AppConfig) IsCritical() bool {
+	return a.Critical == nil || *a.Critical
 }
 
 type ConfigFile struct {
-	WaitCheck    int         `json:"waitCheck"`
-	WaitExit     int         `json:"waitExit"`
-	Applications []AppConfig `json:"applications"`
+	Schema       string            `json:"$schema"`
+	Include      []string          `json:"include"`
+	Vars         map[string]string `json:"vars"`
+	Mode         string            `json:"mode"`
+	WaitCheck    Duration          `json:"waitCheck"`
+	WaitExit     Duration          `json:"waitExit"`
+	Trigger      *TriggerConfig    `json:"trigger"`
+	Applications []AppConfig       `json:"applications"`
+	Groups       []GroupConfig     `json:"groups"`
+
+	StaggerInterval   Duration `json:"staggerInterval"`
+	MaxParallelStarts int      `json:"maxParallelStarts"`
+
+	Profiles       map[string]ProfileConfig `json:"profiles"`
+	DefaultProfile string                   `json:"defaultProfile"`
+
+	files []string // config files and included files
+}
+
+// Files returns the absolute paths of the config files and the files they include
+func (c *ConfigFile) Files() []string {
+	return c.files
 }
 
-func LoadConfigFile(configFile string) (*ConfigFile, error) {
-	file, err := os.ReadFile(configFile)
+// LoadConfigFiles loads JSON, YAML or TOML config files and merges them in
+// order with the files they include, then applies the profile (the default
+// one when empty). The format of the files is guessed from their extension
+// when empty.
+func LoadConfigFiles(configFiles []string, format string, profile string) (*ConfigFile, error) {
+	v := &validator{positions: make(positions)}
+	loader := &configLoader{v: v}
+	doc, err := loader.loadLayers(configFiles, format)
 	if err != nil {
 		return nil, err
 	}
+	if err := applyProfile(doc, profile, v); err != nil {
+		return nil, err
+	}
 
+	file, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
 	var jsonData ConfigFile
-	err = json.Unmarshal([]byte(file), &jsonData)
+	if err := json.Unmarshal(file, &jsonData); err != nil && len(v.problems) == 0 {
+		v.addf("", "%s", err)
+	}
+	newInterpolator(v, jsonData.Vars).expandConfig(&jsonData)
+	v.checkConfig(&jsonData)
 
-	return &jsonData, err
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+
+	jsonData.files = loader.files
+	return &jsonData, nil
+}
+
+// EffectiveConfig returns the config files merged with the files they
+// include and the profile as JSON, before the variables are interpolated
+func EffectiveConfig(configFiles []string, format string, profile string) ([]byte, error) {
+	v := &validator{positions: make(positions)}
+	doc, err := (&configLoader{v: v}).loadLayers(configFiles, format)
+	if err != nil {
+		return nil, err
+	}
+	if err := applyProfile(doc, profile, v); err != nil {
+		return nil, err
+	}
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(doc, "", "    ")
 }
@@ -0,0 +1,36 @@
+package internal
+
+import (
+	"os"
+	"time"
+)
+
+// ConfigWatcher detects the changes of the config files by polling their
+// modification time
+type ConfigWatcher struct {
+	files    []string
+	modTimes map[string]time.Time
+}
+
+func NewConfigWatcher(files []string) *ConfigWatcher {
+	w := &ConfigWatcher{files: files, modTimes: make(map[string]time.Time)}
+	w.Changed()
+	return w
+}
+
+// Changed tells if one of the files was modified, created or removed since
+// the previous call
+func (w *ConfigWatcher) Changed() bool {
+	changed := false
+	for _, file := range w.files {
+		var modTime time.Time
+		if info, err := os.Stat(file); err == nil {
+			modTime = info.ModTime()
+		}
+		if previous, found := w.modTimes[file]; found && !previous.Equal(modTime) {
+			changed = true
+		}
+		w.modTimes[file] = modTime
+	}
+	return changed
+}
@@ -0,0 +1,96 @@
+package internal
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// DisplayName returns the name of the app or the name of its executable
+func (a AppConfig) DisplayName() string {
+	if a.Name != "" {
+		return a.Name
+	}
+	return filepath.Base(a.Path)
+}
+
+// checkDependencies checks that app names are unique, that dependencies
+// refer to existing apps and that there is no dependency cycle
+func (v *validator) checkDependencies(path string, apps []AppConfig) {
+	names := make(map[string]int)
+	for i, app := range apps {
+		appPath := indexPath(path, i)
+		if app.Name == "" {
+			if len(app.DependsOn) > 0 {
+				v.addf(childPath(appPath, "dependsOn"), "app %s has dependencies but no name", app.Path)
+			}
+			continue
+		}
+		if _, found := names[app.Name]; found {
+			v.addf(childPath(appPath, "name"), "duplicated app name %q", app.Name)
+			continue
+		}
+		names[app.Name] = i
+	}
+
+	valid := true
+	for i, app := range apps {
+		for j, dep := range app.DependsOn {
+			if _, found := names[dep]; !found {
+				v.addf(indexPath(childPath(indexPath(path, i), "dependsOn"), j), "app %q depends on unknown app %q", app.Name, dep)
+				valid = false
+			}
+		}
+	}
+	if !valid {
+		return
+	}
+
+	// depth-first search, a cycle is found when reaching an app being visited
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make([]int, len(apps))
+	var stack []int
+	var visit func(i int)
+	visit = func(i int) {
+		switch state[i] {
+		case visiting:
+			start := 0
+			for stack[start] != i {
+				start++
+			}
+			var cycle []string
+			for _, j := range stack[start:] {
+				cycle = append(cycle, apps[j].Name)
+			}
+			cycle = append(cycle, apps[i].Name)
+			v.addf(childPath(indexPath(path, i), "dependsOn"), "dependency cycle: %s", strings.Join(cycle, " -> "))
+			return
+		case visited:
+			return
+		}
+		state[i] = visiting
+		stack = append(stack, i)
+		for _, dep := range apps[i].DependsOn {
+			visit(names[dep])
+		}
+		stack = stack[:len(stack)-1]
+		state[i] = visited
+	}
+	for i := range apps {
+		visit(i)
+	}
+}
+
+// dependents returns, for each app name, the names of the apps depending on it
+func dependents(apps []AppConfig) map[string][]string {
+	res := make(map[string][]string)
+	for _, app := range apps {
+		for _, dep := range app.DependsOn {
+			res[dep] = append(res[dep], app.Name)
+		}
+	}
+	return res
+}
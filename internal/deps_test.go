@@ -0,0 +1,76 @@
+package internal
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestCheckDependencies(t *testing.T) {
+	app := func(name string, deps ...string) AppConfig {
+		return AppConfig{Name: name, Path: "/bin/" + name, DependsOn: deps}
+	}
+
+	tests := []struct {
+		name     string
+		apps     []AppConfig
+		problems []string // "path: message"
+	}{
+		{
+			name: "no dependencies",
+			apps: []AppConfig{app("a"), app("b")},
+		},
+		{
+			name: "chain",
+			apps: []AppConfig{app("a", "b"), app("b", "c"), app("c")},
+		},
+		{
+			name: "diamond",
+			apps: []AppConfig{app("a", "b", "c"), app("b", "d"), app("c", "d"), app("d")},
+		},
+		{
+			name:     "self dependency",
+			apps:     []AppConfig{app("a", "a")},
+			problems: []string{"apps[0].dependsOn: dependency cycle: a -> a"},
+		},
+		{
+			name:     "cycle",
+			apps:     []AppConfig{app("a", "b"), app("b", "c"), app("c", "a")},
+			problems: []string{"apps[0].dependsOn: dependency cycle: a -> b -> c -> a"},
+		},
+		{
+			name:     "cycle reached from another app",
+			apps:     []AppConfig{app("a", "b"), app("b", "c"), app("c", "b")},
+			problems: []string{"apps[1].dependsOn: dependency cycle: b -> c -> b"},
+		},
+		{
+			name:     "unknown dependency",
+			apps:     []AppConfig{app("a", "b")},
+			problems: []string{`apps[0].dependsOn[0]: app "a" depends on unknown app "b"`},
+		},
+		{
+			name:     "duplicated name",
+			apps:     []AppConfig{app("a"), app("a")},
+			problems: []string{`apps[1].name: duplicated app name "a"`},
+		},
+		{
+			name:     "unnamed app with dependencies",
+			apps:     []AppConfig{app("a"), {Path: "/bin/b", DependsOn: []string{"a"}}},
+			problems: []string{"apps[1].dependsOn: app /bin/b has dependencies but no name"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := &validator{positions: make(positions)}
+			v.checkDependencies("apps", tt.apps)
+
+			var problems []string
+			for _, problem := range v.problems {
+				problems = append(problems, problem.Path+": "+problem.Message)
+			}
+			if !slices.Equal(problems, tt.problems) {
+				t.Errorf("problems = %q, want %q", problems, tt.problems)
+			}
+		})
+	}
+}
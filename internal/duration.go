@@ -0,0 +1,47 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// durationPattern matches the Go duration strings accepted in the config
+const durationPattern = `^(0|([0-9]+(\.[0-9]+)?(ns|us|µs|ms|s|m|h))+)$`
+
+// Duration is a duration of the config, given as a number of seconds
+// (e.g. 2 or 0.5) or as a Go duration string (e.g. "1500ms" or "2m")
+type Duration time.Duration
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var value any
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	switch value := value.(type) {
+	case float64:
+		*d = Duration(value * float64(time.Second))
+	case string:
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q", value)
+		}
+		*d = Duration(parsed)
+	default:
+		return fmt.Errorf("invalid duration %s", data)
+	}
+	return nil
+}
+
+func (d Duration) String() string {
+	return time.Duration(d).String()
+}
+
+func (d Duration) jsonSchema() map[string]any {
+	return map[string]any{
+		"type":        []string{"number", "string"},
+		"minimum":     0,
+		"pattern":     durationPattern,
+		"description": `number of seconds or duration such as "1500ms" or "2m"`,
+	}
+}
@@ -0,0 +1,53 @@
+package internal
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestDurationUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		data    string
+		want    time.Duration
+		wantErr bool
+	}{
+		{data: `0`, want: 0},
+		{data: `2`, want: 2 * time.Second},
+		{data: `0.5`, want: 500 * time.Millisecond},
+		{data: `"1500ms"`, want: 1500 * time.Millisecond},
+		{data: `"2m"`, want: 2 * time.Minute},
+		{data: `"1h30m"`, want: 90 * time.Minute},
+		{data: `"0"`, want: 0},
+		{data: `"2 minutes"`, wantErr: true},
+		{data: `"2"`, wantErr: true},
+		{data: `true`, wantErr: true},
+		{data: `[1]`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.data, func(t *testing.T) {
+			var d Duration
+			err := json.Unmarshal([]byte(tt.data), &d)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Unmarshal(%s) error = %v, want error %v", tt.data, err, tt.wantErr)
+			}
+			if err == nil && time.Duration(d) != tt.want {
+				t.Errorf("Unmarshal(%s) = %s, want %s", tt.data, d, tt.want)
+			}
+		})
+	}
+}
+
+func TestDurationInConfig(t *testing.T) {
+	var app AppConfig
+	if err := json.Unmarshal([]byte(`{"stopTimeout": "250ms", "restartDelay": 3}`), &app); err != nil {
+		t.Fatal(err)
+	}
+	if app.StopTimeout != Duration(250*time.Millisecond) || app.RestartDelay != Duration(3*time.Second) {
+		t.Errorf("stopTimeout = %s, restartDelay = %s", app.StopTimeout, app.RestartDelay)
+	}
+	if got := durationOrDefault(app.RestartMaxDelay, time.Minute); got != time.Minute {
+		t.Errorf("durationOrDefault() = %s, want the default", got)
+	}
+}
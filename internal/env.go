@@ -0,0 +1,108 @@
+package internal
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// appEnvVars returns the variables defined for an app,
+// the ones from Env taking precedence over the ones from EnvFile
+func appEnvVars(app AppConfig) (map[string]string, error) {
+	vars := make(map[string]string)
+	if app.EnvFile != "" {
+		fileVars, err := loadEnvFile(app.EnvFile)
+		if err != nil {
+			return nil, err
+		}
+		for k, v := range fileVars {
+			vars[k] = v
+		}
+	}
+	for k, v := range app.Env {
+		vars[k] = v
+	}
+	return vars, nil
+}
+
+// buildEnv returns the environment used to start an app: the app variables
+// are either merged into the current environment or replace it
+func buildEnv(app AppConfig, vars map[string]string) []string {
+	if !app.ReplaceEnv && len(vars) == 0 {
+		// nil means the child inherits the current environment
+		return nil
+	}
+
+	env := []string{}
+	if !app.ReplaceEnv {
+		env = append(env, os.Environ()...)
+	}
+
+	// sort keys to get a predictable environment,
+	// duplicated keys are resolved by exec (last one wins)
+	keys := make([]string, 0, len(vars))
+	for k := range vars {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		env = append(env, k+"="+vars[k])
+	}
+	return env
+}
+
+// loadEnvFile reads a dotenv-like file: one KEY=VALUE per line,
+// empty lines and lines starting with # are ignored
+func loadEnvFile(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open env file: %w", err)
+	}
+	defer f.Close()
+
+	vars := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+
+		key, value, found := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !found || key == "" {
+			return nil, fmt.Errorf("invalid line %d in env file %s", lineNum, path)
+		}
+		vars[key] = unquote(strings.TrimSpace(value))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("cannot read env file: %w", err)
+	}
+	return vars, nil
+}
+
+func unquote(value string) string {
+	if len(value) >= 2 {
+		first, last := value[0], value[len(value)-1]
+		if (first == '"' || first == '\'') && first == last {
+			return value[1 : len(value)-1]
+		}
+	}
+	return value
+}
+
+// parseEnv converts a list of KEY=VALUE strings into a map
+func parseEnv(env []string) map[string]string {
+	vars := make(map[string]string, len(env))
+	for _, kv := range env {
+		if k, v, found := strings.Cut(kv, "="); found && k != "" {
+			vars[k] = v
+		}
+	}
+	return vars
+}
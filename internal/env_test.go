@@ -0,0 +1,113 @@
+package internal
+
+import (
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestLoadEnvFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    map[string]string
+		wantErr string
+	}{
+		{
+			name: "values",
+			content: `# comment
+  # indented comment
+
+PLAIN=value
+SPACED = spaced value 
+export EXPORTED=1
+DOUBLE="quoted value"
+SINGLE='single # quoted'
+UNBALANCED="open
+EQUALS=a=b
+EMPTY=
+`,
+			want: map[string]string{
+				"PLAIN":      "value",
+				"SPACED":     "spaced value",
+				"EXPORTED":   "1",
+				"DOUBLE":     "quoted value",
+				"SINGLE":     "single # quoted",
+				"UNBALANCED": `"open`,
+				"EQUALS":     "a=b",
+				"EMPTY":      "",
+			},
+		},
+		{name: "crlf", content: "A=1\r\nB=\"2\"\r\n", want: map[string]string{"A": "1", "B": "2"}},
+		{name: "missing equal sign", content: "A=1\n\nNOVALUE\n", wantErr: "invalid line 3"},
+		{name: "missing key", content: "=value\n", wantErr: "invalid line 1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), ".env")
+			if err := os.WriteFile(file, []byte(tt.content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			got, err := loadEnvFile(file)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("loadEnvFile() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !maps.Equal(got, tt.want) {
+				t.Errorf("loadEnvFile() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := loadEnvFile(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("missing env file accepted")
+	}
+}
+
+func TestAppEnvVars(t *testing.T) {
+	file := filepath.Join(t.TempDir(), ".env")
+	if err := os.WriteFile(file, []byte("A=file\nB=file\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	got, err := appEnvVars(AppConfig{EnvFile: file, Env: map[string]string{"B": "env", "C": "env"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]string{"A": "file", "B": "env", "C": "env"}; !maps.Equal(got, want) {
+		t.Errorf("appEnvVars() = %q, want %q", got, want)
+	}
+}
+
+func TestBuildEnv(t *testing.T) {
+	t.Setenv("RUNSYNCAPPS_TEST", "inherited")
+	vars := map[string]string{"B": "2", "A": "1"}
+
+	if env := buildEnv(AppConfig{}, nil); env != nil {
+		t.Errorf("without vars, env = %q, want nil to inherit the environment", env)
+	}
+	if env := buildEnv(AppConfig{ReplaceEnv: true}, nil); env == nil || len(env) != 0 {
+		t.Errorf("replaceEnv without vars, env = %q, want an empty environment", env)
+	}
+
+	env := buildEnv(AppConfig{}, vars)
+	if !slices.Contains(env, "RUNSYNCAPPS_TEST=inherited") {
+		t.Errorf("merged env doesn't contain the current environment")
+	}
+	if got := env[len(env)-2:]; !slices.Equal(got, []string{"A=1", "B=2"}) {
+		t.Errorf("merged env ends with %q, want the sorted app variables", got)
+	}
+
+	env = buildEnv(AppConfig{ReplaceEnv: true}, vars)
+	if !slices.Equal(env, []string{"A=1", "B=2"}) {
+		t.Errorf("replaced env = %q, want only the app variables", env)
+	}
+}
@@ -0,0 +1,144 @@
+package internal
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+const (
+	ExitActionStop    = "stop"
+	ExitActionRestart = "restart"
+	ExitActionIgnore  = "ignore"
+
+	// exit codes of runsyncapps
+	ExitClean          = 0
+	ExitError          = 1
+	ExitStartupFailure = 2
+	ExitAppCrashed     = 3
+)
+
+// ExitRule tells what to do when an app exits in a given way, "on" is either
+// an exit code, "nonzero", "signal" (killed by any signal), a signal name
+// (e.g. SIGSEGV), "unhealthy" (failed liveness probe) or "any"
+type ExitRule struct {
+	On     ExitCondition `json:"on"`
+	Action string        `json:"action"`
+}
+
+// ExitCondition is the "on" value of an exit rule, an exit code
+// may be given as a number or as a string
+type ExitCondition string
+
+func (c *ExitCondition) UnmarshalJSON(data []byte) error {
+	var value any
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	switch value := value.(type) {
+	case float64:
+		if value != math.Trunc(value) {
+			return fmt.Errorf("invalid exit code %s", data)
+		}
+		*c = ExitCondition(strconv.Itoa(int(value)))
+	case string:
+		*c = ExitCondition(value)
+	default:
+		return fmt.Errorf("invalid exit condition %s", data)
+	}
+	return nil
+}
+
+func (c ExitCondition) jsonSchema() map[string]any {
+	return map[string]any{
+		"type": []string{"integer", "string"},
+	}
+}
+
+// matches checks if the rule applies to the exit of a process
+func (r ExitRule) matches(exit processExit) bool {
+	on := strings.ToLower(strings.TrimSpace(string(r.On)))
+	switch on {
+	case "any":
+		return true
+	case "nonzero":
+		return exit.exitCode > 0
+	case "signal":
+		return exit.signal != ""
+	case "unhealthy":
+		return exit.unhealthy
+	}
+	if code, err := strconv.Atoi(on); err == nil {
+		return exit.signal == "" && !exit.unhealthy && exit.exitCode == code
+	}
+	return exit.signal != "" && strings.TrimPrefix(on, "sig") == strings.TrimPrefix(strings.ToLower(exit.signal), "sig")
+}
+
+// exitAction returns the action of the first rule matching the exit of a process
+func exitAction(rules []ExitRule, exit processExit) (string, bool) {
+	for _, rule := range rules {
+		if rule.matches(exit) {
+			return strings.ToLower(rule.Action), true
+		}
+	}
+	return "", false
+}
+
+// failed tells if a process exited with an error, a process with an
+// unknown exit code (not started by runsyncapps) is not considered failed
+func (e processExit) failed() bool {
+	return e.err != nil || e.unhealthy || e.signal != "" || e.exitCode > 0
+}
+
+// StartupError is returned when the apps cannot be started or are not ready
+type StartupError struct {
+	Err error
+}
+
+func (e *StartupError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *StartupError) Unwrap() error {
+	return e.Err
+}
+
+// AppExitError is returned when the apps have been stopped because one of them failed
+type AppExitError struct {
+	App      string
+	ExitCode int
+	Signal   string
+	Err      error
+}
+
+func (e *AppExitError) Error() string {
+	switch {
+	case e.Err != nil:
+		return fmt.Sprintf("app %s failed: %s", e.App, e.Err)
+	case e.Signal != "":
+		return fmt.Sprintf("app %s was killed by signal %s", e.App, e.Signal)
+	}
+	return fmt.Sprintf("app %s exited with code %d", e.App, e.ExitCode)
+}
+
+func (e *AppExitError) Unwrap() error {
+	return e.Err
+}
+
+// ExitCode returns the exit code of runsyncapps matching an error
+func ExitCode(err error) int {
+	var startupErr *StartupError
+	var appErr *AppExitError
+	switch {
+	case err == nil:
+		return ExitClean
+	case errors.As(err, &startupErr):
+		return ExitStartupFailure
+	case errors.As(err, &appErr):
+		return ExitAppCrashed
+	}
+	return ExitError
+}
@@ -0,0 +1,126 @@
+package internal
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestExitRuleMatches(t *testing.T) {
+	exitCode := func(code int) processExit { return processExit{exitCode: code} }
+	killed := func(signal string) processExit { return processExit{exitCode: -1, signal: signal} }
+	unhealthy := processExit{exitCode: -1, signal: "SIGKILL", unhealthy: true}
+
+	tests := []struct {
+		on   ExitCondition
+		exit processExit
+		want bool
+	}{
+		{"any", exitCode(0), true},
+		{"any", killed("SIGTERM"), true},
+		{"0", exitCode(0), true},
+		{"0", exitCode(1), false},
+		{" 2 ", exitCode(2), true},
+		{"2", unhealthy, false},
+		{"nonzero", exitCode(1), true},
+		{"nonzero", exitCode(0), false},
+		{"nonzero", exitCode(-1), false},
+		{"signal", killed("SIGSEGV"), true},
+		{"signal", exitCode(1), false},
+		{"SIGSEGV", killed("SIGSEGV"), true},
+		{"segv", killed("SIGSEGV"), true},
+		{"sigsegv", killed("SEGV"), true},
+		{"SIGSEGV", killed("SIGTERM"), false},
+		{"SIGSEGV", exitCode(11), false},
+		{"unhealthy", unhealthy, true},
+		{"unhealthy", killed("SIGKILL"), false},
+		{"Any", exitCode(3), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%s/%+v", tt.on, tt.exit), func(t *testing.T) {
+			if got := (ExitRule{On: tt.on}).matches(tt.exit); got != tt.want {
+				t.Errorf("matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExitConditionUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		data    string
+		want    ExitCondition
+		wantErr bool
+	}{
+		{data: `0`, want: "0"},
+		{data: `137`, want: "137"},
+		{data: `"nonzero"`, want: "nonzero"},
+		{data: `"1"`, want: "1"},
+		{data: `1.5`, wantErr: true},
+		{data: `true`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.data, func(t *testing.T) {
+			var got ExitCondition
+			err := json.Unmarshal([]byte(tt.data), &got)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("UnmarshalJSON() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("UnmarshalJSON() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExitAction(t *testing.T) {
+	rules := []ExitRule{
+		{On: "0", Action: "Ignore"},
+		{On: "nonzero", Action: ExitActionRestart},
+		{On: "any", Action: ExitActionStop},
+	}
+
+	tests := []struct {
+		exit   processExit
+		action string
+	}{
+		{processExit{exitCode: 0}, ExitActionIgnore},
+		{processExit{exitCode: 1}, ExitActionRestart},
+		{processExit{exitCode: -1, signal: "SIGTERM"}, ExitActionStop},
+	}
+	for _, tt := range tests {
+		action, found := exitAction(rules, tt.exit)
+		if !found || action != tt.action {
+			t.Errorf("exitAction(%+v) = %q, %v, want %q", tt.exit, action, found, tt.action)
+		}
+	}
+
+	if action, found := exitAction(nil, processExit{}); found {
+		t.Errorf("exitAction without rules = %q, want no action", action)
+	}
+}
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"no error", nil, ExitClean},
+		{"startup failure", &StartupError{Err: errors.New("not ready")}, ExitStartupFailure},
+		{"app crashed", &AppExitError{App: "a", ExitCode: 1}, ExitAppCrashed},
+		{"wrapped app crash", fmt.Errorf("group: %w", &AppExitError{App: "a", Signal: "SIGSEGV"}), ExitAppCrashed},
+		{"joined errors", errors.Join(nil, &StartupError{Err: errors.New("x")}), ExitStartupFailure},
+		{"other error", errors.New("invalid config"), ExitError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ExitCode(tt.err); got != tt.want {
+				t.Errorf("ExitCode() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
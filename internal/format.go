@@ -0,0 +1,58 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	FormatJSON = "json"
+	FormatYAML = "yaml"
+	FormatTOML = "toml"
+)
+
+// configFormat returns the format of the config file, guessed from its
+// extension when not set, JSON being the default
+func configFormat(configFile string, format string) (string, error) {
+	if format == "" {
+		switch strings.ToLower(filepath.Ext(configFile)) {
+		case ".yaml", ".yml":
+			return FormatYAML, nil
+		case ".toml":
+			return FormatTOML, nil
+		}
+		return FormatJSON, nil
+	}
+
+	switch format = strings.ToLower(format); format {
+	case FormatJSON, FormatYAML, FormatTOML:
+		return format, nil
+	case "yml":
+		return FormatYAML, nil
+	}
+	return "", fmt.Errorf("unknown config format %q", format)
+}
+
+// toJSON converts YAML and TOML documents to JSON so that all the formats
+// are decoded into the config structs the same way
+func toJSON(data []byte, format string) ([]byte, error) {
+	var doc map[string]any
+	switch format {
+	case FormatYAML:
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return nil, err
+		}
+	case FormatTOML:
+		if err := toml.Unmarshal(data, &doc); err != nil {
+			return nil, err
+		}
+	default:
+		return data, nil
+	}
+	return json.Marshal(doc)
+}
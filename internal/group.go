@@ -0,0 +1,36 @@
+package internal
+
+import (
+	"fmt"
+)
+
+// GroupConfig is a set of apps kept in sync independently of the other groups
+type GroupConfig struct {
+	Name         string         `json:"name"`
+	WaitCheck    Duration       `json:"waitCheck"`
+	WaitExit     Duration       `json:"waitExit"`
+	Trigger      *TriggerConfig `json:"trigger"`
+	Applications []AppConfig    `json:"applications"`
+}
+
+// AppGroups returns the groups of the config, the top-level applications
+// form a single unnamed group
+func (c *ConfigFile) AppGroups() []GroupConfig {
+	if len(c.Groups) > 0 {
+		return c.Groups
+	}
+	return []GroupConfig{{
+		WaitCheck:    c.WaitCheck,
+		WaitExit:     c.WaitExit,
+		Trigger:      c.Trigger,
+		Applications: c.Applications,
+	}}
+}
+
+// DisplayName returns the name of the group or its position in the config
+func (g GroupConfig) DisplayName(index int) string {
+	if g.Name != "" {
+		return g.Name
+	}
+	return fmt.Sprintf("group %d", index+1)
+}
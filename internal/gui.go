@@ -1,14 +1,120 @@
 package internal
 
 import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
 	"github.com/getlantern/systray"
 )
 
-func OnReadyUI() {
+const trayRefreshInterval = 1 * time.Second
+
+// ErrQuit is the cause of the stop when the user quits from the systray
+var ErrQuit = errors.New("quit from systray")
+
+// TrayGroup is a group listed in the systray menu with its current state
+type TrayGroup struct {
+	Name  string
+	State func() string
+}
+
+// TrayUI is the systray menu: it lists the groups with their state and
+// lets the user switch between the profiles of the config and reload it
+type TrayUI struct {
+	mu           sync.Mutex
+	groups       []TrayGroup
+	profiles     []string
+	profile      string
+	switches     chan string
+	reloads      chan struct{}
+	profileItems map[string]*systray.MenuItem
+}
+
+func NewTrayUI(profiles []string, profile string) *TrayUI {
+	return &TrayUI{
+		profiles:     profiles,
+		profile:      profile,
+		switches:     make(chan string, 1),
+		reloads:      make(chan struct{}, 1),
+		profileItems: make(map[string]*systray.MenuItem),
+	}
+}
+
+// ProfileSwitches returns the channel receiving the profiles selected by the user
+func (ui *TrayUI) ProfileSwitches() <-chan string {
+	return ui.switches
+}
+
+// Reloads returns the channel receiving the config reloads requested by the user
+func (ui *TrayUI) Reloads() <-chan struct{} {
+	return ui.reloads
+}
+
+// SetGroups replaces the groups listed in the menu
+func (ui *TrayUI) SetGroups(groups []TrayGroup) {
+	ui.mu.Lock()
+	defer ui.mu.Unlock()
+	ui.groups = groups
+}
+
+// SetProfile checks the current profile in the menu
+func (ui *TrayUI) SetProfile(profile string) {
+	ui.mu.Lock()
+	defer ui.mu.Unlock()
+	ui.profile = profile
+	for name, item := range ui.profileItems {
+		if name == profile {
+			item.Check()
+		} else {
+			item.Uncheck()
+		}
+	}
+}
+
+func (ui *TrayUI) OnReady() {
 	systray.SetTemplateIcon(SysTrayIcon, SysTrayIcon)
 	systray.SetTitle("RunSyncApps")
 	systray.SetTooltip("RunSyncApps")
 
+	// Groups menu, refreshed with the state of each group
+	mGroups := systray.AddMenuItem("Groups", "Groups")
+	go ui.refreshGroups(mGroups)
+
+	// Profiles menu, the profile switch is handled by the receiver of the switches
+	if len(ui.profiles) > 0 {
+		mProfiles := systray.AddMenuItem("Profile", "Profile")
+		ui.mu.Lock()
+		for _, name := range ui.profiles {
+			mProfile := mProfiles.AddSubMenuItemCheckbox(name, name, name == ui.profile)
+			ui.profileItems[name] = mProfile
+			go func(name string) {
+				for range mProfile.ClickedCh {
+					select {
+					case ui.switches <- name:
+					default:
+						// a switch is already pending
+					}
+				}
+			}(name)
+		}
+		ui.mu.Unlock()
+	}
+
+	// Reload menu, the reload is handled by the receiver of the reloads
+	mReload := systray.AddMenuItem("Reload", "Reload the config")
+	go func() {
+		for range mReload.ClickedCh {
+			select {
+			case ui.reloads <- struct{}{}:
+			default:
+				// a reload is already pending
+			}
+		}
+	}()
+	systray.AddSeparator()
+
 	// Exit menu
 	mQuitOrig := systray.AddMenuItem("Quit", "Quit")
 	go func() {
@@ -16,3 +122,29 @@ func OnReadyUI() {
 		systray.Quit()
 	}()
 }
+
+// refreshGroups updates the groups submenu, the items of the groups which
+// are gone are hidden as menu items cannot be removed
+func (ui *TrayUI) refreshGroups(mGroups *systray.MenuItem) {
+	var items []*systray.MenuItem
+	for {
+		ui.mu.Lock()
+		groups := ui.groups
+		ui.mu.Unlock()
+
+		for i, group := range groups {
+			title := fmt.Sprintf("%s: %s", group.Name, group.State())
+			if i == len(items) {
+				item := mGroups.AddSubMenuItem(title, group.Name)
+				item.Disable()
+				items = append(items, item)
+			}
+			items[i].SetTitle(title)
+			items[i].Show()
+		}
+		for _, item := range items[len(groups):] {
+			item.Hide()
+		}
+		time.Sleep(trayRefreshInterval)
+	}
+}
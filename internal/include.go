@@ -0,0 +1,178 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// mergedByName are the arrays whose items are merged by name when layering
+// config files, the other arrays are replaced
+var mergedByName = []string{"applications", "groups"}
+
+// configLoader loads config files and the files they include, checking
+// each of them against the schema before merging them
+type configLoader struct {
+	v       *validator
+	loading []string // files being loaded, to detect include cycles
+	files   []string // files loaded, watched for changes
+}
+
+// loadLayers loads the config files and merges them in order, the later
+// files overriding the earlier ones
+func (l *configLoader) loadLayers(configFiles []string, format string) (map[string]any, error) {
+	merged := make(map[string]any)
+	for _, configFile := range configFiles {
+		doc, pos, err := l.load(configFile, format)
+		if err != nil {
+			return nil, err
+		}
+		mergeDocuments(merged, doc, "", "", l.v.positions, pos)
+		l.v.positions[""] = pos[""]
+	}
+	return merged, nil
+}
+
+// load loads a config file on top of the files it includes, the included
+// files are resolved relative to the including file
+func (l *configLoader) load(configFile string, format string) (map[string]any, positions, error) {
+	absFile, err := filepath.Abs(configFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	if slices.Contains(l.loading, absFile) {
+		return nil, nil, fmt.Errorf("include cycle: %s is included by itself", configFile)
+	}
+	l.loading = append(l.loading, absFile)
+	if !slices.Contains(l.files, absFile) {
+		l.files = append(l.files, absFile)
+	}
+	defer func() { l.loading = l.loading[:len(l.loading)-1] }()
+
+	doc, filePos, err := l.parse(configFile, format)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var includes []string
+	if list, ok := doc["include"].([]any); ok {
+		for _, include := range list {
+			pattern := fmt.Sprint(include)
+			if !filepath.IsAbs(pattern) {
+				pattern = filepath.Join(filepath.Dir(configFile), pattern)
+			}
+			matches, err := filepath.Glob(pattern)
+			if err != nil {
+				return nil, nil, fmt.Errorf("invalid include %s in %s: %w", include, configFile, err)
+			}
+			if len(matches) == 0 && !hasGlobMeta(pattern) {
+				return nil, nil, fmt.Errorf("included file %s not found in %s", include, configFile)
+			}
+			includes = append(includes, matches...)
+		}
+	}
+	delete(doc, "include")
+
+	merged := make(map[string]any)
+	pos := positions{"": filePos[""]}
+	for _, include := range includes {
+		// the format of the included files is guessed from their extension
+		incDoc, incPos, err := l.load(include, "")
+		if err != nil {
+			return nil, nil, err
+		}
+		mergeDocuments(merged, incDoc, "", "", pos, incPos)
+	}
+	mergeDocuments(merged, doc, "", "", pos, filePos)
+	return merged, pos, nil
+}
+
+// parse reads a config file and checks it against the schema
+func (l *configLoader) parse(configFile string, format string) (map[string]any, positions, error) {
+	format, err := configFormat(configFile, format)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	file, err := os.ReadFile(configFile)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	pos := configPositions(configFile, file, format)
+	file, err = toJSON(file, format)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid %s config %s: %w", format, configFile, err)
+	}
+
+	var doc map[string]any
+	if err := json.Unmarshal(file, &doc); err != nil {
+		return nil, nil, fmt.Errorf("invalid config %s: %w", configFile, err)
+	}
+
+	// type errors are found when checking the document against the schema
+	// so that all the problems are reported at once
+	filePositions := l.v.positions
+	l.v.positions = pos
+	err = l.v.checkDocument(doc)
+	l.v.positions = filePositions
+	return doc, pos, err
+}
+
+// mergeDocuments merges the src object into dst: nested objects are merged,
+// the items of the mergedByName arrays are merged with the item of the same
+// name or appended and the other values are replaced. The positions of the
+// merged values are updated accordingly.
+func mergeDocuments(dst map[string]any, src map[string]any, dstPath string, srcPath string, dstPos positions, srcPos positions) {
+	for key, value := range src {
+		dstChild, srcChild := childPath(dstPath, key), childPath(srcPath, key)
+		switch value := value.(type) {
+		case map[string]any:
+			if existing, ok := dst[key].(map[string]any); ok {
+				mergeDocuments(existing, value, dstChild, srcChild, dstPos, srcPos)
+				continue
+			}
+		case []any:
+			if existing, ok := dst[key].([]any); ok && slices.Contains(mergedByName, key) {
+				dst[key] = mergeByName(existing, value, dstChild, srcChild, dstPos, srcPos)
+				continue
+			}
+		}
+		dst[key] = value
+		dstPos.move(dstChild, srcPos, srcChild)
+	}
+}
+
+func mergeByName(dst []any, src []any, dstPath string, srcPath string, dstPos positions, srcPos positions) []any {
+	for i, item := range src {
+		if object, ok := item.(map[string]any); ok {
+			if j := indexByName(dst, object["name"]); j >= 0 {
+				mergeDocuments(dst[j].(map[string]any), object, indexPath(dstPath, j), indexPath(srcPath, i), dstPos, srcPos)
+				continue
+			}
+		}
+		dst = append(dst, item)
+		dstPos.move(indexPath(dstPath, len(dst)-1), srcPos, indexPath(srcPath, i))
+	}
+	return dst
+}
+
+// indexByName returns the index of the object with the given name, -1 if not found
+func indexByName(items []any, name any) int {
+	if name, ok := name.(string); !ok || name == "" {
+		return -1
+	}
+	for i, item := range items {
+		if object, ok := item.(map[string]any); ok && object["name"] == name {
+			return i
+		}
+	}
+	return -1
+}
+
+func hasGlobMeta(pattern string) bool {
+	return strings.ContainsAny(pattern, `*?[`)
+}
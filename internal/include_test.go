@@ -0,0 +1,111 @@
+package internal
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMergeDocuments(t *testing.T) {
+	tests := []struct {
+		name string
+		dst  map[string]any
+		src  map[string]any
+		want map[string]any
+	}{
+		{
+			name: "values are replaced",
+			dst:  map[string]any{"mode": "once", "waitCheck": 1.0},
+			src:  map[string]any{"mode": "supervise"},
+			want: map[string]any{"mode": "supervise", "waitCheck": 1.0},
+		},
+		{
+			name: "objects are merged",
+			dst:  map[string]any{"vars": map[string]any{"a": "1", "b": "2"}},
+			src:  map[string]any{"vars": map[string]any{"b": "3", "c": "4"}},
+			want: map[string]any{"vars": map[string]any{"a": "1", "b": "3", "c": "4"}},
+		},
+		{
+			name: "other arrays are replaced",
+			dst:  map[string]any{"include": []any{"a.json", "b.json"}},
+			src:  map[string]any{"include": []any{"c.json"}},
+			want: map[string]any{"include": []any{"c.json"}},
+		},
+		{
+			name: "apps are merged by name",
+			dst: map[string]any{"applications": []any{
+				map[string]any{"name": "a", "path": "/bin/a", "args": []any{"-x"}},
+				map[string]any{"name": "b", "path": "/bin/b"},
+			}},
+			src: map[string]any{"applications": []any{
+				map[string]any{"name": "b", "args": []any{"-y"}},
+				map[string]any{"name": "c", "path": "/bin/c"},
+			}},
+			want: map[string]any{"applications": []any{
+				map[string]any{"name": "a", "path": "/bin/a", "args": []any{"-x"}},
+				map[string]any{"name": "b", "path": "/bin/b", "args": []any{"-y"}},
+				map[string]any{"name": "c", "path": "/bin/c"},
+			}},
+		},
+		{
+			name: "unnamed apps are appended",
+			dst:  map[string]any{"applications": []any{map[string]any{"path": "/bin/a"}}},
+			src:  map[string]any{"applications": []any{map[string]any{"path": "/bin/a"}}},
+			want: map[string]any{"applications": []any{map[string]any{"path": "/bin/a"}, map[string]any{"path": "/bin/a"}}},
+		},
+		{
+			name: "apps of groups are merged by name",
+			dst: map[string]any{"groups": []any{
+				map[string]any{"name": "g", "applications": []any{map[string]any{"name": "a", "path": "/bin/a"}}},
+			}},
+			src: map[string]any{"groups": []any{
+				map[string]any{"name": "g", "applications": []any{map[string]any{"name": "a", "disabled": true}}},
+			}},
+			want: map[string]any{"groups": []any{
+				map[string]any{"name": "g", "applications": []any{map[string]any{"name": "a", "path": "/bin/a", "disabled": true}}},
+			}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mergeDocuments(tt.dst, tt.src, "", "", make(positions), make(positions))
+			if !reflect.DeepEqual(tt.dst, tt.want) {
+				t.Errorf("merged = %v, want %v", tt.dst, tt.want)
+			}
+		})
+	}
+}
+
+func TestMergeByNamePositions(t *testing.T) {
+	dst := []any{map[string]any{"name": "a", "path": "/bin/a"}}
+	src := []any{
+		map[string]any{"name": "b", "path": "/bin/b"},
+		map[string]any{"name": "a", "args": []any{"-x"}},
+	}
+	dstPos := positions{
+		"applications[0]":      {"base.json", 2, "applications[0]"},
+		"applications[0].path": {"base.json", 3, "applications[0].path"},
+	}
+	srcPos := positions{
+		"applications[0]":      {"config.json", 5, "applications[0]"},
+		"applications[0].path": {"config.json", 6, "applications[0].path"},
+		"applications[1].args": {"config.json", 9, "applications[1].args"},
+	}
+
+	merged := mergeByName(dst, src, "applications", "applications", dstPos, srcPos)
+	if len(merged) != 2 {
+		t.Fatalf("merged %d apps, want 2", len(merged))
+	}
+
+	// the positions point to the file and path the values come from
+	want := positions{
+		"applications[0]":      {"base.json", 2, "applications[0]"},
+		"applications[0].path": {"base.json", 3, "applications[0].path"},
+		"applications[0].args": {"config.json", 9, "applications[1].args"},
+		"applications[1]":      {"config.json", 5, "applications[0]"},
+		"applications[1].path": {"config.json", 6, "applications[0].path"},
+	}
+	if !reflect.DeepEqual(dstPos, want) {
+		t.Errorf("positions = %v, want %v", dstPos, want)
+	}
+}
@@ -0,0 +1,123 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// varPattern matches ${NAME}, ${NAME:-default} and the $$ escape
+var varPattern = regexp.MustCompile(`\$\$|\$\{([A-Za-z_][A-Za-z0-9_.-]*)(:-([^}]*))?\}`)
+
+// interpolator expands the variables of the config values, the vars of the
+// config take precedence over the environment variables
+type interpolator struct {
+	v         *validator
+	vars      map[string]string
+	resolved  map[string]string
+	resolving map[string]bool
+}
+
+func newInterpolator(v *validator, vars map[string]string) *interpolator {
+	return &interpolator{
+		v:         v,
+		vars:      vars,
+		resolved:  make(map[string]string),
+		resolving: make(map[string]bool),
+	}
+}
+
+// expand replaces the variables of a value and a leading ~ by the home
+// directory, undefined variables are reported at the path of the value
+func (in *interpolator) expand(path string, value string) string {
+	if value == "~" || strings.HasPrefix(value, "~/") || strings.HasPrefix(value, `~\`) {
+		if home, err := os.UserHomeDir(); err == nil {
+			value = filepath.Join(home, value[1:])
+		} else {
+			in.v.addf(path, "cannot expand ~: %s", err)
+		}
+	}
+
+	return varPattern.ReplaceAllStringFunc(value, func(match string) string {
+		if match == "$$" {
+			return "$"
+		}
+		groups := varPattern.FindStringSubmatch(match)
+		name, hasDefault, def := groups[1], groups[2] != "", groups[3]
+		if value, found := in.lookup(name); found && (value != "" || !hasDefault) {
+			return value
+		}
+		if hasDefault {
+			return def
+		}
+		in.v.addf(path, "undefined variable %q", name)
+		return ""
+	})
+}
+
+// lookup returns the value of a variable, the vars can reference other vars
+func (in *interpolator) lookup(name string) (string, bool) {
+	raw, found := in.vars[name]
+	if !found {
+		return os.LookupEnv(name)
+	}
+	if value, done := in.resolved[name]; done {
+		return value, true
+	}
+	if in.resolving[name] {
+		in.v.addf(childPath("vars", name), "variable %q references itself", name)
+		return "", true
+	}
+
+	in.resolving[name] = true
+	value := in.expand(childPath("vars", name), raw)
+	in.resolving[name] = false
+	in.resolved[name] = value
+	return value, true
+}
+
+// expandApps expands the variables of the paths, arguments, environment
+// and working directories of the apps
+func (in *interpolator) expandApps(path string, apps []AppConfig) {
+	for i := range apps {
+		app := &apps[i]
+		appPath := indexPath(path, i)
+
+		app.Path = in.expand(childPath(appPath, "path"), app.Path)
+		for j := range app.Args {
+			app.Args[j] = in.expand(indexPath(childPath(appPath, "args"), j), app.Args[j])
+		}
+		for key, value := range app.Env {
+			app.Env[key] = in.expand(childPath(childPath(appPath, "env"), key), value)
+		}
+		app.EnvFile = in.expand(childPath(appPath, "envFile"), app.EnvFile)
+		app.WorkingDir = in.expand(childPath(appPath, "workingDir"), app.WorkingDir)
+		app.Stdout = in.expand(childPath(appPath, "stdout"), app.Stdout)
+		app.Stderr = in.expand(childPath(appPath, "stderr"), app.Stderr)
+		if app.Match != nil {
+			app.Match.Path = in.expand(childPath(appPath, "match.path"), app.Match.Path)
+		}
+		for _, probe := range []struct {
+			name   string
+			config *ProbeConfig
+		}{{"readiness", app.Readiness}, {"liveness", app.Liveness}} {
+			if probe.config == nil {
+				continue
+			}
+			probePath := childPath(appPath, probe.name)
+			probe.config.Path = in.expand(childPath(probePath, "path"), probe.config.Path)
+			for j := range probe.config.Command {
+				probe.config.Command[j] = in.expand(indexPath(childPath(probePath, "command"), j), probe.config.Command[j])
+			}
+		}
+	}
+}
+
+// expandConfig expands the variables of the apps of the config
+func (in *interpolator) expandConfig(c *ConfigFile) {
+	in.expandApps("applications", c.Applications)
+	for i := range c.Groups {
+		in.expandApps(childPath(indexPath("groups", i), "applications"), c.Groups[i].Applications)
+	}
+}
@@ -0,0 +1,76 @@
+package internal
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestInterpolatorExpand(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+	t.Setenv("RSA_TEST_ENV", "from-env")
+	t.Setenv("RSA_TEST_EMPTY", "")
+	t.Setenv("RSA_TEST_SHADOWED", "env")
+
+	vars := map[string]string{
+		"dir":               "/opt/${app}",
+		"app":               "editor",
+		"RSA_TEST_SHADOWED": "var",
+		"loop":              "${loop2}",
+		"loop2":             "${loop}",
+	}
+
+	tests := []struct {
+		name     string
+		value    string
+		want     string
+		problems []string
+	}{
+		{name: "plain", value: "/usr/bin/app", want: "/usr/bin/app"},
+		{name: "var", value: "${app}.exe", want: "editor.exe"},
+		{name: "nested vars", value: "${dir}/bin", want: "/opt/editor/bin"},
+		{name: "env", value: "x-${RSA_TEST_ENV}", want: "x-from-env"},
+		{name: "var before env", value: "${RSA_TEST_SHADOWED}", want: "var"},
+		{name: "default unused", value: "${app:-other}", want: "editor"},
+		{name: "default when undefined", value: "${RSA_TEST_UNDEFINED:-fallback}", want: "fallback"},
+		{name: "default when empty", value: "${RSA_TEST_EMPTY:-fallback}", want: "fallback"},
+		{name: "empty without default", value: "[${RSA_TEST_EMPTY}]", want: "[]"},
+		{name: "escape", value: "$${app} costs $$5", want: "${app} costs $5"},
+		{name: "lone dollar", value: "$app", want: "$app"},
+		{name: "home", value: "~", want: home},
+		{name: "home path", value: "~/bin/app", want: filepath.Join(home, "/bin/app")},
+		{name: "tilde inside", value: "a~/b", want: "a~/b"},
+		{
+			name:     "undefined",
+			value:    "${RSA_TEST_UNDEFINED}/app",
+			want:     "/app",
+			problems: []string{`value: undefined variable "RSA_TEST_UNDEFINED"`},
+		},
+		{
+			name:     "cycle",
+			value:    "${loop}",
+			want:     "",
+			problems: []string{`vars.loop: variable "loop" references itself`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := &validator{positions: make(positions)}
+			got := newInterpolator(v, vars).expand("value", tt.value)
+			if got != tt.want {
+				t.Errorf("expand(%q) = %q, want %q", tt.value, got, tt.want)
+			}
+
+			var problems []string
+			for _, problem := range v.problems {
+				problems = append(problems, problem.Path+": "+problem.Message)
+			}
+			if !slices.Equal(problems, tt.problems) {
+				t.Errorf("problems = %q, want %q", problems, tt.problems)
+			}
+		})
+	}
+}
@@ -0,0 +1,291 @@
+package internal
+
+import (
+	"cmp"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"slices"
+	"strings"
+
+	ps "github.com/keybase/go-ps"
+)
+
+const (
+	AdoptFirst  = "first"
+	AdoptAll    = "all"
+	AdoptNewest = "newest"
+	AdoptOldest = "oldest"
+
+	ClosedWhenAny = "any"
+	ClosedWhenAll = "all"
+)
+
+// MatchConfig tells how to recognize a running instance of an app,
+// all the criteria which are set must match
+type MatchConfig struct {
+	Name         string `json:"name"`
+	Path         string `json:"path"`
+	Cmdline      string `json:"cmdline"`
+	CmdlineRegex string `json:"cmdlineRegex"`
+	User         string `json:"user"`
+}
+
+// processMatcher checks the running processes against the criteria of an app:
+// its match block when set, otherwise its path and launch parameters
+type processMatcher struct {
+	app          AppConfig
+	envVars      map[string]string
+	path         string
+	cmdlineRegex *regexp.Regexp
+}
+
+func newProcessMatcher(app AppConfig, envVars map[string]string) (*processMatcher, error) {
+	m := &processMatcher{app: app, envVars: envVars, path: resolvePath(app.Path)}
+	if app.Match == nil {
+		return m, nil
+	}
+
+	if app.Match.Path != "" {
+		m.path = resolvePath(app.Match.Path)
+	}
+	if app.Match.CmdlineRegex != "" {
+		re, err := regexp.Compile(app.Match.CmdlineRegex)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cmdlineRegex for %s: %w", app.DisplayName(), err)
+		}
+		m.cmdlineRegex = re
+	}
+	return m, nil
+}
+
+// findExistingPid returns the pid of the running instance of the app,
+// -1 if there is none and an error if several instances match
+func (p *ProcessHander) findExistingPid(app AppConfig, envVars map[string]string, procs []ps.Process) (int, error) {
+	pids, err := p.findExistingPids(app, envVars, procs)
+	if err != nil {
+		return -1, err
+	}
+	switch len(pids) {
+	case 0:
+		return -1, nil
+	case 1:
+		return pids[0], nil
+	}
+	p.logger.Warn("Several running instances match", "app", app.DisplayName(), "pids", fmt.Sprint(pids))
+	return -1, fmt.Errorf("ambiguous match for %s, several instances are running (pids %v)", app.DisplayName(), pids)
+}
+
+// adoptedPids returns the pids of the running instances of the app
+// to adopt according to its adopt setting
+func (p *ProcessHander) adoptedPids(app AppConfig, envVars map[string]string, procs []ps.Process) ([]int, error) {
+	adopt := strings.ToLower(app.Adopt)
+	if adopt == "" {
+		pid, err := p.findExistingPid(app, envVars, procs)
+		if err != nil || pid == -1 {
+			return nil, err
+		}
+		return []int{pid}, nil
+	}
+
+	pids, err := p.findExistingPids(app, envVars, procs)
+	if err != nil || len(pids) <= 1 {
+		return pids, err
+	}
+
+	switch adopt {
+	case AdoptAll:
+		return pids, nil
+	case AdoptFirst:
+		return pids[:1], nil
+	case AdoptNewest, AdoptOldest:
+		// pids without start time are considered as the oldest ones
+		startTimes := make(map[int]uint64, len(pids))
+		for _, pid := range pids {
+			startTimes[pid], _ = processStartTime(pid)
+		}
+		slices.SortStableFunc(pids, func(a, b int) int {
+			return cmp.Compare(startTimes[a], startTimes[b])
+		})
+		if adopt == AdoptNewest {
+			return pids[len(pids)-1:], nil
+		}
+		return pids[:1], nil
+	}
+	return nil, fmt.Errorf("unknown adopt value %q for %s", app.Adopt, app.DisplayName())
+}
+
+// findExistingPids returns the pids of all the running instances of the app
+func (p *ProcessHander) findExistingPids(app AppConfig, envVars map[string]string, procs []ps.Process) ([]int, error) {
+	m, err := newProcessMatcher(app, envVars)
+	if err != nil {
+		return nil, err
+	}
+
+	var pids []int
+	for _, proc := range procs {
+		if proc.Pid() == os.Getpid() {
+			continue
+		}
+		if p.matches(m, proc) {
+			p.logger.Debug("Found running app", "app", app.DisplayName(), "pid", proc.Pid())
+			pids = append(pids, proc.Pid())
+		}
+	}
+	return pids, nil
+}
+
+func (p *ProcessHander) matches(m *processMatcher, proc ps.Process) bool {
+	procPath, _ := proc.Path()
+	match := m.app.Match
+	if match == nil {
+		return samePathResolved(procPath, m.path) && p.matchesLaunchParams(proc.Pid(), m.app, m.envVars)
+	}
+
+	if match.Name != "" && !p.matchesName(proc, procPath, match.Name) {
+		return false
+	}
+
+	if match.Path != "" && !samePathResolved(procPath, m.path) {
+		return false
+	}
+
+	if match.Cmdline != "" || m.cmdlineRegex != nil {
+		args, err := processCommandLine(proc.Pid())
+		if err != nil {
+			return false
+		}
+		cmdLine := strings.Join(args, " ")
+		if match.Cmdline != "" && !strings.Contains(cmdLine, match.Cmdline) {
+			return false
+		}
+		if m.cmdlineRegex != nil && !m.cmdlineRegex.MatchString(cmdLine) {
+			return false
+		}
+	}
+
+	if match.User != "" {
+		owner, err := processUser(proc.Pid())
+		if err != nil || !sameUser(owner, match.User) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchesName compares the executable name of a process, either from its resolved
+// path or from its command line as the executable may be a symlink (python3 -> python3.12)
+func (p *ProcessHander) matchesName(proc ps.Process, procPath string, name string) bool {
+	names := []string{proc.Executable()}
+	if procPath != "" {
+		names = append(names, filepath.Base(procPath))
+	}
+	if args, err := processCommandLine(proc.Pid()); err == nil && len(args) > 0 {
+		names = append(names, filepath.Base(args[0]))
+	}
+	for _, n := range names {
+		if sameExecutableName(n, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// check the launch parameters of a running process against the app config,
+// parameters which are not set or cannot be read are not compared
+func (p *ProcessHander) matchesLaunchParams(pid int, app AppConfig, envVars map[string]string) bool {
+	if len(app.Args) > 0 {
+		args, err := processArgs(pid)
+		if err != nil {
+			p.logger.Debug("Cannot read process args", "pid", pid, "error", err)
+		} else if !slices.Equal(args, app.Args) {
+			p.logger.Debug("Process args differ", "pid", pid, "args", args)
+			return false
+		}
+	}
+
+	if app.WorkingDir != "" {
+		cwd, err := processWorkingDir(pid)
+		if err != nil {
+			p.logger.Debug("Cannot read process working dir", "pid", pid, "error", err)
+		} else if !samePath(cwd, app.WorkingDir) {
+			p.logger.Debug("Process working dir differs", "pid", pid, "workingDir", cwd)
+			return false
+		}
+	}
+
+	if len(envVars) > 0 {
+		env, err := processEnv(pid)
+		if err != nil {
+			p.logger.Debug("Cannot read process env", "pid", pid, "error", err)
+		} else {
+			for k, v := range envVars {
+				if env[k] != v {
+					p.logger.Debug("Process env differs", "pid", pid, "key", k)
+					return false
+				}
+			}
+		}
+	}
+
+	return true
+}
+
+// resolvePath returns the absolute path of a file with its symlinks evaluated
+func resolvePath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	}
+	return path
+}
+
+// samePathResolved compares the path of a process to an already resolved path
+func samePathResolved(procPath string, resolved string) bool {
+	if procPath == "" {
+		return false
+	}
+	return samePath(procPath, resolved) || samePath(resolvePath(procPath), resolved)
+}
+
+func samePath(a, b string) bool {
+	if absA, err := filepath.Abs(a); err == nil {
+		a = absA
+	}
+	if absB, err := filepath.Abs(b); err == nil {
+		b = absB
+	}
+	if runtime.GOOS == "windows" {
+		return strings.EqualFold(a, b)
+	}
+	return a == b
+}
+
+// sameExecutableName compares executable names, the .exe extension
+// is optional on Windows where names are case insensitive
+func sameExecutableName(a, b string) bool {
+	if runtime.GOOS == "windows" {
+		a = strings.TrimSuffix(strings.ToLower(a), ".exe")
+		b = strings.TrimSuffix(strings.ToLower(b), ".exe")
+	}
+	return a == b
+}
+
+// sameUser compares user names, on Windows names are
+// case insensitive and DOMAIN\user matches user
+func sameUser(owner string, expected string) bool {
+	if runtime.GOOS != "windows" {
+		return owner == expected
+	}
+	if strings.EqualFold(owner, expected) {
+		return true
+	}
+	if _, name, found := strings.Cut(owner, `\`); found && !strings.Contains(expected, `\`) {
+		return strings.EqualFold(name, expected)
+	}
+	return false
+}
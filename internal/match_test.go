@@ -0,0 +1,65 @@
+package internal
+
+import (
+	"io"
+	"log/slog"
+	"os/exec"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+
+	ps "github.com/keybase/go-ps"
+)
+
+// startSleep starts a process which is killed at the end of the test
+func startSleep(t *testing.T, path string, arg string) int {
+	t.Helper()
+	cmd := exec.Command(path, arg)
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		cmd.Process.Kill()
+		cmd.Wait()
+	})
+	return cmd.Process.Pid
+}
+
+func TestAdoptedPids(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("the args of the processes are compared on linux")
+	}
+	path, err := exec.LookPath("sleep")
+	if err != nil {
+		t.Skip("sleep not found")
+	}
+	p := NewProcessHander(slog.New(slog.NewTextHandler(io.Discard, nil)), "", nil)
+	app := AppConfig{Path: path, Args: []string{"3601"}}
+
+	first := startSleep(t, path, "3601")
+	startSleep(t, path, "3602")
+	procs, err := ps.Processes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pids, err := p.adoptedPids(app, nil, procs); err != nil || !slices.Equal(pids, []int{first}) {
+		t.Errorf("single instance: adoptedPids() = %v, %v, want [%d]", pids, err, first)
+	}
+
+	second := startSleep(t, path, "3601")
+	procs, err = ps.Processes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pids, err := p.adoptedPids(app, nil, procs); err == nil || !strings.Contains(err.Error(), "ambiguous match") {
+		t.Errorf("several instances: adoptedPids() = %v, %v, want an ambiguous match error", pids, err)
+	}
+
+	app.Adopt = AdoptAll
+	pids, err := p.adoptedPids(app, nil, procs)
+	slices.Sort(pids)
+	if err != nil || !slices.Equal(pids, []int{first, second}) {
+		t.Errorf("adopt all: adoptedPids() = %v, %v, want [%d %d]", pids, err, first, second)
+	}
+}
@@ -0,0 +1,203 @@
+package internal
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+const (
+	OutputDiscard = "discard"
+	OutputInherit = "inherit"
+	OutputLog     = "log"
+	OutputFile    = "file"
+
+	defaultLogMaxSize  = 10 // megabytes
+	defaultLogMaxFiles = 3
+)
+
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// outputWriters returns the writers for the stdout and stderr of an app, the
+// returned closers must be called once the process has exited
+func (p *ProcessHander) outputWriters(app AppConfig) (io.Writer, io.Writer, []io.Closer, error) {
+	var closers []io.Closer
+	files := make(map[string]*rotatingFile)
+
+	writer := func(mode string, stream string, std *os.File) (io.Writer, error) {
+		switch strings.ToLower(mode) {
+		case "", OutputDiscard:
+			return nil, nil
+		case OutputInherit:
+			return std, nil
+		case OutputLog:
+			w := &logWriter{logger: p.logger.With("app", app.DisplayName(), "stream", stream)}
+			closers = append(closers, w)
+			return w, nil
+		}
+
+		path := mode
+		if strings.ToLower(mode) == OutputFile {
+			name := unsafeFileChars.ReplaceAllString(app.DisplayName(), "_")
+			path = fmt.Sprintf("%s_%s.log", name, stream)
+		}
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(p.outputDir, path)
+		}
+
+		// stdout and stderr can share the same file
+		if f, found := files[path]; found {
+			return f, nil
+		}
+		f, err := newRotatingFile(path, app.LogMaxSize, app.LogMaxFiles)
+		if err != nil {
+			return nil, err
+		}
+		files[path] = f
+		closers = append(closers, f)
+		return f, nil
+	}
+
+	stdout, err := writer(app.Stdout, "stdout", os.Stdout)
+	if err != nil {
+		closeAll(closers)
+		return nil, nil, nil, err
+	}
+	stderr, err := writer(app.Stderr, "stderr", os.Stderr)
+	if err != nil {
+		closeAll(closers)
+		return nil, nil, nil, err
+	}
+	return stdout, stderr, closers, nil
+}
+
+func closeAll(closers []io.Closer) {
+	for _, c := range closers {
+		c.Close()
+	}
+}
+
+// logWriter forwards each line written to it to the logger
+type logWriter struct {
+	logger *slog.Logger
+	buf    []byte
+	mu     sync.Mutex
+}
+
+func (w *logWriter) Write(data []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.buf = append(w.buf, data...)
+	for {
+		end := bytes.IndexByte(w.buf, '\n')
+		if end < 0 {
+			break
+		}
+		w.logger.Info(strings.TrimRight(string(w.buf[:end]), "\r"))
+		w.buf = w.buf[end+1:]
+	}
+	return len(data), nil
+}
+
+// Close logs the last line if it doesn't end with a line break
+func (w *logWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if len(w.buf) > 0 {
+		w.logger.Info(string(w.buf))
+		w.buf = nil
+	}
+	return nil
+}
+
+// rotatingFile is a file which is rotated once it reaches its maximum size,
+// the previous files are renamed with a numeric suffix (app.log.1, app.log.2...)
+type rotatingFile struct {
+	path     string
+	maxSize  int64
+	maxFiles int
+	file     *os.File
+	size     int64
+	mu       sync.Mutex
+}
+
+func newRotatingFile(path string, maxSizeMB int, maxFiles int) (*rotatingFile, error) {
+	if maxSizeMB <= 0 {
+		maxSizeMB = defaultLogMaxSize
+	}
+	if maxFiles <= 0 {
+		maxFiles = defaultLogMaxFiles
+	}
+	f := &rotatingFile{path: path, maxSize: int64(maxSizeMB) * 1024 * 1024, maxFiles: maxFiles}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *rotatingFile) open() error {
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("cannot open output file: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	f.file = file
+	f.size = info.Size()
+	return nil
+}
+
+func (f *rotatingFile) Write(data []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return 0, os.ErrClosed
+	}
+	if f.size > 0 && f.size+int64(len(data)) > f.maxSize {
+		if err := f.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := f.file.Write(data)
+	f.size += int64(n)
+	return n, err
+}
+
+// rotate shifts the previous files and starts a new one, the oldest one is removed
+func (f *rotatingFile) rotate() error {
+	f.file.Close()
+	f.file = nil
+
+	os.Remove(fmt.Sprintf("%s.%d", f.path, f.maxFiles))
+	for i := f.maxFiles - 1; i >= 1; i-- {
+		os.Rename(fmt.Sprintf("%s.%d", f.path, i), fmt.Sprintf("%s.%d", f.path, i+1))
+	}
+	if err := os.Rename(f.path, f.path+".1"); err != nil {
+		return err
+	}
+	return f.open()
+}
+
+func (f *rotatingFile) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return nil
+	}
+	err := f.file.Close()
+	f.file = nil
+	return err
+}
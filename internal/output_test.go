@@ -0,0 +1,103 @@
+package internal
+
+import (
+	"bytes"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestRotatingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	if err := os.WriteFile(path, []byte("old\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	f := &rotatingFile{path: path, maxSize: 10, maxFiles: 2}
+	if err := f.open(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the existing content counts in the size, a write is never split
+	for _, line := range []string{"first\n", "second\n", "third\n", "fourth\n", "a very long line\n"} {
+		if _, err := f.Write([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Write([]byte("closed\n")); err == nil {
+		t.Error("write to a closed file succeeded")
+	}
+
+	// the oldest files are removed once maxFiles rotated files are kept
+	want := map[string]string{
+		"app.log":   "a very long line\n",
+		"app.log.1": "fourth\n",
+		"app.log.2": "third\n",
+	}
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	if wantNames := []string{"app.log", "app.log.1", "app.log.2"}; !slices.Equal(names, wantNames) {
+		t.Fatalf("files = %q, want %q", names, wantNames)
+	}
+	for name, content := range want {
+		data, err := os.ReadFile(filepath.Join(filepath.Dir(path), name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != content {
+			t.Errorf("%s = %q, want %q", name, data, content)
+		}
+	}
+}
+
+func TestNewRotatingFileDefaults(t *testing.T) {
+	f, err := newRotatingFile(filepath.Join(t.TempDir(), "app.log"), 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if f.maxSize != defaultLogMaxSize*1024*1024 || f.maxFiles != defaultLogMaxFiles {
+		t.Errorf("maxSize = %d, maxFiles = %d, want the defaults", f.maxSize, f.maxFiles)
+	}
+}
+
+func TestLogWriter(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey || a.Key == slog.LevelKey {
+				return slog.Attr{}
+			}
+			return a
+		},
+	}))
+	w := &logWriter{logger: logger}
+
+	// lines split across writes are logged once complete, the partial last
+	// line is flushed on close
+	for _, data := range []string{"first li", "ne\r\nsecond\nthi", "rd\n", "partial"} {
+		if n, err := w.Write([]byte(data)); err != nil || n != len(data) {
+			t.Fatalf("Write() = %d, %v", n, err)
+		}
+	}
+	if got := strings.Count(buf.String(), "\n"); got != 3 {
+		t.Errorf("%d lines logged before close, want 3", got)
+	}
+	w.Close()
+
+	want := `msg="first line"` + "\n" + "msg=second\n" + "msg=third\n" + "msg=partial\n"
+	if buf.String() != want {
+		t.Errorf("logged:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
@@ -0,0 +1,130 @@
+package internal
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// LaunchPacer spreads the launches of the apps at startup: the apps are
+// launched in the order of their tickets, two launches are separated by the
+// stagger interval and at most maxParallel apps are starting at once, an app
+// starting until it is ready. It is shared by the groups so that the limits
+// apply to all the apps.
+type LaunchPacer struct {
+	mu          sync.Mutex
+	interval    time.Duration
+	maxParallel int
+	next        time.Time // earliest time of the next launch
+	starting    int       // apps launched and not ready yet
+	tickets     uint64    // number of tickets reserved
+	serving     uint64    // ticket of the next app to launch
+	cancelled   map[uint64]bool
+	changed     chan struct{} // closed when the next app may be launched
+}
+
+// launchTicket is the place of an app in the launch queue
+type launchTicket struct {
+	l        *LaunchPacer
+	number   uint64
+	acquired bool
+	released bool
+}
+
+func NewLaunchPacer(interval Duration, maxParallel int) *LaunchPacer {
+	return &LaunchPacer{
+		interval:    time.Duration(interval),
+		maxParallel: maxParallel,
+		cancelled:   make(map[uint64]bool),
+		changed:     make(chan struct{}),
+	}
+}
+
+// Configure replaces the limits, used when the config is reloaded
+func (l *LaunchPacer) Configure(interval Duration, maxParallel int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.interval = time.Duration(interval)
+	l.maxParallel = maxParallel
+	l.notify()
+}
+
+// reserve queues an app to be launched, a nil pacer returns a nil ticket
+// which doesn't wait
+func (l *LaunchPacer) reserve() *launchTicket {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	t := &launchTicket{l: l, number: l.tickets}
+	l.tickets++
+	return t
+}
+
+// acquire waits for the turn of the app to be launched
+func (t *launchTicket) acquire(ctx context.Context) error {
+	if t == nil {
+		return nil
+	}
+	l := t.l
+	for {
+		l.mu.Lock()
+		now := time.Now()
+		turn := t.number == l.serving && (l.maxParallel <= 0 || l.starting < l.maxParallel)
+		if turn && !now.Before(l.next) {
+			t.acquired = true
+			l.starting++
+			l.serving++
+			l.next = now.Add(l.interval)
+			l.skipCancelled()
+			l.notify()
+			l.mu.Unlock()
+			return nil
+		}
+		var timer <-chan time.Time
+		if turn {
+			timer = time.After(l.next.Sub(now))
+		}
+		changed := l.changed
+		l.mu.Unlock()
+
+		select {
+		case <-timer:
+		case <-changed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// release frees the launch slot once the app is ready or has failed, or
+// gives up the turn of an app which was not launched
+func (t *launchTicket) release() {
+	if t == nil || t.released {
+		return
+	}
+	l := t.l
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	t.released = true
+	if t.acquired {
+		l.starting--
+	} else {
+		l.cancelled[t.number] = true
+		l.skipCancelled()
+	}
+	l.notify()
+}
+
+func (l *LaunchPacer) skipCancelled() {
+	for l.cancelled[l.serving] {
+		delete(l.cancelled, l.serving)
+		l.serving++
+	}
+}
+
+func (l *LaunchPacer) notify() {
+	close(l.changed)
+	l.changed = make(chan struct{})
+}
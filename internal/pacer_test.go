@@ -0,0 +1,134 @@
+package internal
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLaunchPacerOrder(t *testing.T) {
+	l := NewLaunchPacer(0, 1)
+	tickets := make([]*launchTicket, 5)
+	for i := range tickets {
+		tickets[i] = l.reserve()
+	}
+
+	// the apps ask for their turn in the reverse order of their tickets
+	var mu sync.Mutex
+	var order []int
+	var wg sync.WaitGroup
+	for i := len(tickets) - 1; i >= 0; i-- {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if err := tickets[i].acquire(context.Background()); err != nil {
+				t.Error(err)
+				return
+			}
+			mu.Lock()
+			order = append(order, i)
+			mu.Unlock()
+			tickets[i].release()
+		}(i)
+		time.Sleep(5 * time.Millisecond)
+	}
+	wg.Wait()
+
+	if want := []int{0, 1, 2, 3, 4}; !slices.Equal(order, want) {
+		t.Errorf("launch order = %v, want %v", order, want)
+	}
+}
+
+func TestLaunchPacerMaxParallel(t *testing.T) {
+	l := NewLaunchPacer(0, 2)
+	first, second, third := l.reserve(), l.reserve(), l.reserve()
+	ctx := context.Background()
+	if err := first.acquire(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := second.acquire(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	acquired := make(chan struct{})
+	go func() {
+		third.acquire(ctx)
+		close(acquired)
+	}()
+	select {
+	case <-acquired:
+		t.Fatal("third app launched while two apps are starting")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	first.release()
+	select {
+	case <-acquired:
+	case <-time.After(time.Second):
+		t.Fatal("third app not launched once the first one is ready")
+	}
+}
+
+func TestLaunchPacerInterval(t *testing.T) {
+	interval := 50 * time.Millisecond
+	l := NewLaunchPacer(Duration(interval), 0)
+	ctx := context.Background()
+
+	var launches []time.Time
+	for range 3 {
+		ticket := l.reserve()
+		if err := ticket.acquire(ctx); err != nil {
+			t.Fatal(err)
+		}
+		launches = append(launches, time.Now())
+		ticket.release()
+	}
+	for i := 1; i < len(launches); i++ {
+		if gap := launches[i].Sub(launches[i-1]); gap < interval {
+			t.Errorf("launch %d started %s after the previous one, want at least %s", i, gap, interval)
+		}
+	}
+}
+
+func TestLaunchPacerReleasedTicket(t *testing.T) {
+	l := NewLaunchPacer(0, 1)
+	skipped, next := l.reserve(), l.reserve()
+
+	// an app failing before its launch gives up its turn
+	skipped.release()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := next.acquire(ctx); err != nil {
+		t.Fatalf("acquire() after a released ticket: %v", err)
+	}
+	next.release()
+	next.release()
+	if l.starting != 0 {
+		t.Errorf("starting = %d after release, want 0", l.starting)
+	}
+}
+
+func TestLaunchPacerCancel(t *testing.T) {
+	l := NewLaunchPacer(Duration(time.Hour), 0)
+	first, second := l.reserve(), l.reserve()
+	if err := first.acquire(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := second.acquire(ctx); err == nil {
+		t.Error("acquire() succeeded before the stagger interval")
+	}
+}
+
+func TestLaunchPacerNil(t *testing.T) {
+	var l *LaunchPacer
+	ticket := l.reserve()
+	if err := ticket.acquire(context.Background()); err != nil {
+		t.Errorf("acquire() on a nil pacer: %v", err)
+	}
+	ticket.release()
+}
@@ -0,0 +1,210 @@
+package internal
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// position is the location of a value in the config files, the line is 0
+// when unknown. The path is the one of the value in its file, which differs
+// from the merged config one when the value comes from a layer.
+type position struct {
+	file string
+	line int
+	path string
+}
+
+// positions maps the path of the config values (e.g. applications[0].path)
+// to their position in the config files
+type positions map[string]position
+
+// lookup returns the position of a value, or the position of its closest
+// parent when it is not set in the config files, with the path of the
+// value in its file
+func (pos positions) lookup(path string) (position, string) {
+	for parent := path; ; parent = parentPath(parent) {
+		if p, found := pos[parent]; found {
+			return p, p.path + path[len(parent):]
+		}
+		if parent == "" {
+			return position{}, path
+		}
+	}
+}
+
+// move replaces the positions of a value and its children by the ones
+// of another value, used when merging config documents
+func (pos positions) move(dstPath string, src positions, srcPath string) {
+	for path := range pos {
+		if isChildPath(path, dstPath) {
+			delete(pos, path)
+		}
+	}
+	for path, p := range src {
+		if isChildPath(path, srcPath) {
+			pos[dstPath+path[len(srcPath):]] = p
+		}
+	}
+}
+
+// isChildPath tells if path is the parent path or one of its children
+func isChildPath(path string, parent string) bool {
+	if parent == "" || path == parent {
+		return true
+	}
+	return strings.HasPrefix(path, parent) && strings.ContainsRune(".[", rune(path[len(parent)]))
+}
+
+func childPath(path string, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func indexPath(path string, index int) string {
+	return fmt.Sprintf("%s[%d]", path, index)
+}
+
+func parentPath(path string) string {
+	if i := strings.LastIndexAny(path, ".["); i >= 0 {
+		return path[:i]
+	}
+	return ""
+}
+
+// configPositions finds the lines of the values of a config file,
+// positions are best effort and missing ones are simply not reported
+func configPositions(file string, data []byte, format string) positions {
+	pos := positions{"": {file: file}}
+	switch format {
+	case FormatYAML:
+		var doc yaml.Node
+		if yaml.Unmarshal(data, &doc) == nil && len(doc.Content) > 0 {
+			yamlPositions(pos, file, "", doc.Content[0])
+		}
+	case FormatTOML:
+		tomlPositions(pos, file, data)
+	default:
+		jsonPositions(pos, file, data)
+	}
+	return pos
+}
+
+func yamlPositions(pos positions, file string, path string, node *yaml.Node) {
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			child := childPath(path, node.Content[i].Value)
+			pos[child] = position{file, node.Content[i].Line, child}
+			yamlPositions(pos, file, child, node.Content[i+1])
+		}
+	case yaml.SequenceNode:
+		for i, item := range node.Content {
+			child := indexPath(path, i)
+			pos[child] = position{file, item.Line, child}
+			yamlPositions(pos, file, child, item)
+		}
+	}
+}
+
+func jsonPositions(pos positions, file string, data []byte) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+
+	// lineAt returns the line of the next value after the decoder offset
+	lineAt := func(offset int64) int {
+		for int(offset) < len(data) && strings.IndexByte(" \t\r\n,:", data[offset]) >= 0 {
+			offset++
+		}
+		return bytes.Count(data[:offset], []byte("\n")) + 1
+	}
+
+	var walk func(path string) error
+	walk = func(path string) error {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		switch tok {
+		case json.Delim('{'):
+			for dec.More() {
+				key, err := dec.Token()
+				if err != nil {
+					return err
+				}
+				child := childPath(path, fmt.Sprint(key))
+				pos[child] = position{file, lineAt(dec.InputOffset()), child}
+				if err := walk(child); err != nil {
+					return err
+				}
+			}
+			_, err = dec.Token()
+		case json.Delim('['):
+			for i := 0; dec.More(); i++ {
+				child := indexPath(path, i)
+				pos[child] = position{file, lineAt(dec.InputOffset()), child}
+				if err := walk(child); err != nil {
+					return err
+				}
+			}
+			_, err = dec.Token()
+		}
+		return err
+	}
+	walk("")
+}
+
+// tomlPositions scans the table headers and keys line by line, values
+// spanning several lines and inline tables are not detailed
+func tomlPositions(pos positions, file string, data []byte) {
+	arrays := make(map[string]int) // current index of the arrays of tables
+	table := ""
+
+	// resolve converts a dotted key to a path, using the current index of
+	// the arrays of tables it goes through
+	resolve := func(base string, key string) string {
+		path := base
+		for _, part := range strings.Split(key, ".") {
+			path = childPath(path, strings.Trim(strings.TrimSpace(part), `"'`))
+			if index, found := arrays[path]; found {
+				path = indexPath(path, index)
+			}
+		}
+		return path
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "" || strings.HasPrefix(line, "#"):
+		case strings.HasPrefix(line, "[["):
+			header := strings.TrimSpace(strings.Trim(strings.SplitN(line, "]]", 2)[0], "[ "))
+			parent, name := "", header
+			if i := strings.LastIndex(header, "."); i >= 0 {
+				parent, name = resolve("", header[:i]), header[i+1:]
+			}
+			array := childPath(parent, strings.Trim(strings.TrimSpace(name), `"'`))
+			if _, found := arrays[array]; found {
+				arrays[array]++
+			} else {
+				arrays[array] = 0
+			}
+			table = indexPath(array, arrays[array])
+			pos[table] = position{file, lineNum, table}
+		case strings.HasPrefix(line, "["):
+			table = resolve("", strings.TrimSpace(strings.Trim(strings.SplitN(line, "]", 2)[0], "[ ")))
+			pos[table] = position{file, lineNum, table}
+		default:
+			if key, _, found := strings.Cut(line, "="); found {
+				path := resolve(table, key)
+				pos[path] = position{file, lineNum, path}
+			}
+		}
+	}
+}
@@ -0,0 +1,293 @@
+package internal
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"regexp"
+	"time"
+)
+
+const (
+	ProbeTCP  = "tcp"
+	ProbeHTTP = "http"
+	ProbeFile = "file"
+	ProbeLog  = "log"
+	ProbeExec = "exec"
+
+	ProbeHeartbeat = "heartbeat"
+
+	defaultProbeInterval    = 1 * time.Second
+	defaultProbeTimeout     = 30 * time.Second
+	defaultLivenessTimeout  = 5 * time.Second
+	defaultFailureThreshold = 3
+	defaultHeartbeatMaxAge  = 30 * time.Second
+	defaultLivenessInterval = 10 * time.Second
+)
+
+// readinessProbeTypes and livenessProbeTypes are the probe types allowed for
+// each probe: a file or log line appears once while a liveness probe must
+// keep succeeding, and an old heartbeat doesn't tell that the app is ready
+var (
+	readinessProbeTypes = []string{ProbeTCP, ProbeHTTP, ProbeFile, ProbeLog, ProbeExec}
+	livenessProbeTypes  = []string{ProbeTCP, ProbeHTTP, ProbeExec, ProbeHeartbeat}
+)
+
+type ProbeConfig struct {
+	Type     string   `json:"type"`
+	Address  string   `json:"address"`
+	URL      string   `json:"url"`
+	Path     string   `json:"path"`
+	Pattern  string   `json:"pattern"`
+	Command  []string `json:"command"`
+	Timeout  Duration `json:"timeout"`
+	Interval Duration `json:"interval"`
+
+	// liveness only
+	InitialDelay     Duration `json:"initialDelay"`
+	FailureThreshold int      `json:"failureThreshold"`
+	MaxAge           Duration `json:"maxAge"`
+}
+
+// probe checks the state of an app, it keeps the position
+// reached in the log file between two checks
+type probe struct {
+	config    ProbeConfig
+	pattern   *regexp.Regexp
+	logOffset int64
+	fileTime  time.Time // modification time of the file when the probe was created, zero if missing
+}
+
+// newProbe creates a probe, the state of the probed files is captured so
+// that the probe must be created before the app is launched
+func newProbe(config ProbeConfig) (*probe, error) {
+	pr := &probe{config: config}
+	switch config.Type {
+	case ProbeTCP:
+		if config.Address == "" {
+			return nil, errors.New("tcp probe requires an address")
+		}
+	case ProbeHTTP:
+		if config.URL == "" {
+			return nil, errors.New("http probe requires an url")
+		}
+	case ProbeFile:
+		if config.Path == "" {
+			return nil, errors.New("file probe requires a path")
+		}
+		// a file left over from a previous run must be written again
+		if info, err := os.Stat(config.Path); err == nil {
+			pr.fileTime = info.ModTime()
+		}
+	case ProbeLog:
+		if config.Path == "" {
+			return nil, errors.New("log probe requires a path")
+		}
+		pattern, err := regexp.Compile(config.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid log probe pattern: %w", err)
+		}
+		pr.pattern = pattern
+		// only the lines written from now on are checked
+		if info, err := os.Stat(config.Path); err == nil {
+			pr.logOffset = info.Size()
+		}
+	case ProbeExec:
+		if len(config.Command) == 0 {
+			return nil, errors.New("exec probe requires a command")
+		}
+	case ProbeHeartbeat:
+		if config.Path == "" {
+			return nil, errors.New("heartbeat probe requires a path")
+		}
+	default:
+		return nil, fmt.Errorf("unknown probe type %q", config.Type)
+	}
+	return pr, nil
+}
+
+// reuse forgets the state of the files captured before the launch when an
+// existing instance is reused, the instance has already written its file or
+// log line and doesn't write it again
+func (pr *probe) reuse() {
+	pr.fileTime = time.Time{}
+	pr.logOffset = 0
+}
+
+func (pr *probe) interval() time.Duration {
+	return durationOrDefault(pr.config.Interval, defaultProbeInterval)
+}
+
+func (pr *probe) timeout() time.Duration {
+	return durationOrDefault(pr.config.Timeout, defaultProbeTimeout)
+}
+
+// check runs the probe once, a nil error means success
+func (pr *probe) check(ctx context.Context) error {
+	switch pr.config.Type {
+	case ProbeTCP:
+		var dialer net.Dialer
+		conn, err := dialer.DialContext(ctx, "tcp", pr.config.Address)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+
+	case ProbeHTTP:
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, pr.config.URL, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return err
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			return fmt.Errorf("unexpected status %s", resp.Status)
+		}
+		return nil
+
+	case ProbeFile:
+		info, err := os.Stat(pr.config.Path)
+		if err != nil {
+			return err
+		}
+		if !pr.fileTime.IsZero() && !info.ModTime().After(pr.fileTime) {
+			return errors.New("file not written since the app was launched")
+		}
+		return nil
+
+	case ProbeLog:
+		return pr.checkLog()
+
+	case ProbeExec:
+		return exec.CommandContext(ctx, pr.config.Command[0], pr.config.Command[1:]...).Run()
+
+	case ProbeHeartbeat:
+		info, err := os.Stat(pr.config.Path)
+		if err != nil {
+			return err
+		}
+		maxAge := durationOrDefault(pr.config.MaxAge, defaultHeartbeatMaxAge)
+		if age := time.Since(info.ModTime()); age > maxAge {
+			return fmt.Errorf("heartbeat file not updated for %s", age.Round(time.Second))
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown probe type %q", pr.config.Type)
+}
+
+// checkLog looks for a line matching the pattern in the lines
+// appended to the log file since the previous check
+func (pr *probe) checkLog() error {
+	f, err := os.Open(pr.config.Path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if info, err := f.Stat(); err == nil && info.Size() < pr.logOffset {
+		// the file has been truncated or rotated
+		pr.logOffset = 0
+	}
+	if _, err := f.Seek(pr.logOffset, io.SeekStart); err != nil {
+		return err
+	}
+
+	reader := bufio.NewReader(f)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			// an incomplete line is read again at the next check
+			break
+		}
+		pr.logOffset += int64(len(line))
+		if pr.pattern.MatchString(line) {
+			return nil
+		}
+	}
+	return fmt.Errorf("no line matching %q", pr.config.Pattern)
+}
+
+// waitReady runs the readiness probe until it succeeds, the process exits,
+// the timeout is reached or the context is cancelled
+func (p *ProcessHander) waitReady(ctx context.Context, proc ProcessDetails, pr *probe) error {
+	deadline := time.Now().Add(pr.timeout())
+	for {
+		checkCtx, cancel := context.WithTimeout(ctx, pr.interval())
+		err := pr.check(checkCtx)
+		cancel()
+		if err == nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		p.logger.Debug("App not ready yet", "app", proc.app.DisplayName(), "probe", pr.config.Type, "error", err)
+
+		if !p.isProcessAlive(proc) {
+			return errors.New("process exited before being ready")
+		}
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return fmt.Errorf("readiness probe timeout after %s: %w", pr.timeout(), err)
+		}
+		select {
+		case <-time.After(min(pr.interval(), remaining)):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// watchLiveness runs the liveness probe periodically while the process is running,
+// it returns an error once the probe has failed failureThreshold times in a row
+// and nil if the process exits or the monitoring ends
+func (p *ProcessHander) watchLiveness(proc ProcessDetails, config ProbeConfig, done chan struct{}) error {
+	pr, err := newProbe(config)
+	if err != nil {
+		p.logger.Warn("Invalid liveness probe", "app", proc.app.DisplayName(), "error", err)
+		return nil
+	}
+	interval := durationOrDefault(config.Interval, defaultLivenessInterval)
+	timeout := durationOrDefault(config.Timeout, defaultLivenessTimeout)
+	threshold := config.FailureThreshold
+	if threshold <= 0 {
+		threshold = defaultFailureThreshold
+	}
+
+	wait := time.Duration(config.InitialDelay)
+	failures := 0
+	for {
+		select {
+		case <-time.After(wait):
+		case <-proc.exited:
+			return nil
+		case <-done:
+			return nil
+		}
+		wait = interval
+
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		err := pr.check(ctx)
+		cancel()
+		if err == nil {
+			failures = 0
+			continue
+		}
+
+		failures++
+		p.logger.Warn("Liveness probe failed", "app", proc.app.DisplayName(), "pid", proc.pid, "failures", failures, "error", err)
+		if failures >= threshold {
+			return fmt.Errorf("liveness probe failed %d times: %w", failures, err)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package internal
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFileProbe(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "ready")
+	pr, err := newProbe(ProbeConfig{Type: ProbeFile, Path: file})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pr.check(context.Background()) == nil {
+		t.Error("missing file reported ready")
+	}
+	if err := os.WriteFile(file, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := pr.check(context.Background()); err != nil {
+		t.Errorf("created file not reported ready: %v", err)
+	}
+}
+
+func TestFileProbeLeftOver(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "ready")
+	if err := os.WriteFile(file, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	pr, err := newProbe(ProbeConfig{Type: ProbeFile, Path: file})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pr.check(context.Background()) == nil {
+		t.Error("file left over from a previous run reported ready")
+	}
+	later := time.Now().Add(time.Second)
+	if err := os.Chtimes(file, later, later); err != nil {
+		t.Fatal(err)
+	}
+	if err := pr.check(context.Background()); err != nil {
+		t.Errorf("written file not reported ready: %v", err)
+	}
+}
+
+func TestLogProbe(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "app.log")
+	if err := os.WriteFile(file, []byte("listening on :8080\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	pr, err := newProbe(ProbeConfig{Type: ProbeLog, Path: file, Pattern: "listening"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pr.check(context.Background()) == nil {
+		t.Error("line written before the probe creation matched")
+	}
+
+	// the lines written once the probe is created are checked, even
+	// before the first check
+	f, err := os.OpenFile(file, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString("starting\nlistening on :8081\n")
+	f.Close()
+	if err := pr.check(context.Background()); err != nil {
+		t.Errorf("new matching line not found: %v", err)
+	}
+}
+
+func TestProbeReuse(t *testing.T) {
+	dir := t.TempDir()
+	logFile := filepath.Join(dir, "app.log")
+	readyFile := filepath.Join(dir, "ready")
+	for _, file := range []string{logFile, readyFile} {
+		if err := os.WriteFile(file, []byte("server ready\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		config ProbeConfig
+	}{
+		{"log", ProbeConfig{Type: ProbeLog, Path: logFile, Pattern: "ready"}},
+		{"file", ProbeConfig{Type: ProbeFile, Path: readyFile}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pr, err := newProbe(tt.config)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// a running instance has written its file or line before the probe creation
+			pr.reuse()
+			if err := pr.check(context.Background()); err != nil {
+				t.Errorf("reused instance not reported ready: %v", err)
+			}
+		})
+	}
+}
@@ -1,75 +1,312 @@
 package internal
 
 import (
+	"context"
+	"fmt"
 	"log/slog"
-	"os"
 	"os/exec"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"time"
 
 	ps "github.com/keybase/go-ps"
 )
 
+const (
+	defaultStopSignal  = "KILL"
+	defaultStopTimeout = 10 * time.Second
+	exitPollInterval   = 100 * time.Millisecond
+
+	// time given to the output copy once a process has exited,
+	// descendants still holding its outputs would block it otherwise
+	outputWaitDelay = 1 * time.Second
+)
+
 type ProcessDetails struct {
-	path       string
-	pid        int
-	killOnExit bool
+	appID       int // index of the app, shared by all its instances
+	app         AppConfig
+	path        string
+	pid         int
+	killOnExit  bool
+	stopSignal  string
+	stopTimeout time.Duration
+	group       bool // the process leads its own process group
+	watcher     processWatcher
+	exited      chan struct{} // closed once the process has exited
+	exit        *processExit
+	restarts    []time.Time
 }
 
+// ProcessHander manages the processes of a set of apps through the
+// idle -> starting -> running -> stopping -> idle cycle, it can go through
+// several cycles when supervising the apps
 type ProcessHander struct {
-	logger *slog.Logger
+	logger    *slog.Logger
+	outputDir string       // directory of the app output files with a relative path
+	pacer     *LaunchPacer // spreads the launches at startup, may be nil
+
+	mu        sync.Mutex
+	state     string
+	procs     map[int]ProcessDetails
+	apps      []AppConfig   // apps of the current cycle
+	waitCheck time.Duration // used for the apps without readiness probe
+	update    []AppConfig   // apps of the reloaded config
+	updated   chan struct{} // signals a config reload
 }
 
-func NewProcessHander(logger *slog.Logger) *ProcessHander {
-	return &ProcessHander{logger: logger}
+func NewProcessHander(logger *slog.Logger, outputDir string, pacer *LaunchPacer) *ProcessHander {
+	return &ProcessHander{
+		logger:    logger,
+		outputDir: outputDir,
+		pacer:     pacer,
+		state:     StateIdle,
+		procs:     make(map[int]ProcessDetails),
+		updated:   make(chan struct{}, 1),
+	}
 }
 
-func (p *ProcessHander) StartProcesses(apps []AppConfig) (map[int]ProcessDetails, error) {
-	procs := make(map[int]ProcessDetails)
+// StartProcesses starts the apps and waits for them to be ready, an app is
+// started once all the apps it depends on are ready and its start delay is
+// elapsed, independent apps are started in parallel within the limits of the
+// launch pacer. An app is ready when its readiness probe succeeds or, without
+// probe, after the waitCheck delay. On failure or cancellation of the context,
+// the apps already started are kept so that they can be stopped.
+func (p *ProcessHander) StartProcesses(ctx context.Context, apps []AppConfig, waitCheck time.Duration) error {
+	p.setState(StateStarting)
+	begin := time.Now()
+	elapsed := func() string {
+		return time.Since(begin).Round(time.Millisecond).String()
+	}
+	p.mu.Lock()
+	p.apps, p.waitCheck = apps, waitCheck
+	p.mu.Unlock()
 
 	runningProcs, err := ps.Processes()
 	if err != nil {
 		p.logger.Error("Error listing processed", "error", err)
-		return nil, err
+		return &StartupError{Err: err}
 	}
 
+	// a channel per named app, closed once the app is ready
+	ready := make(map[string]chan struct{})
 	for _, app := range apps {
-		newProc := ProcessDetails{path: app.Path, pid: -1, killOnExit: app.KillOnExit}
+		if app.Name != "" {
+			ready[app.Name] = make(chan struct{})
+		}
+	}
 
-		if app.UseExistingInstance {
-			// check existing processes
-			currentPid, err := p.findPidFromPath(app.Path, runningProcs)
-			if err != nil {
-				p.logger.Warn("Error checking running processes", "error", err)
+	var mu sync.Mutex // protects startErr
+	var wg sync.WaitGroup
+	var startErr error
+	failed := func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return startErr != nil
+	}
+	fail := func(app AppConfig, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if startErr == nil {
+			startErr = fmt.Errorf("cannot start %s: %w", app.DisplayName(), err)
+		}
+	}
+
+	for appID, app := range apps {
+		// the apps which can be launched right away are queued in the config order
+		var ticket *launchTicket
+		if len(app.DependsOn) == 0 && app.StartDelay <= 0 {
+			ticket = p.pacer.reserve()
+		}
+
+		wg.Add(1)
+		go func(appID int, app AppConfig, ticket *launchTicket) {
+			defer wg.Done()
+			// released by launchApp unless a dependency failed
+			defer ticket.release()
+			if app.Name != "" {
+				defer close(ready[app.Name])
+			}
+
+			for _, dep := range app.DependsOn {
+				p.logger.Debug("Waiting for dependency", "app", app.DisplayName(), "dependency", dep)
+				select {
+				case <-ready[dep]:
+				case <-ctx.Done():
+				}
 			}
-			if currentPid != -1 {
-				newProc.pid = currentPid
-				p.logger.Info("Found running app", "path", newProc.path, "pid", newProc.pid)
+			if failed() {
+				return
+			}
+			if ctx.Err() != nil {
+				fail(app, ctx.Err())
+				return
 			}
-		}
 
-		// start app if not found in existing processes
-		if newProc.pid == -1 {
-			cmd := exec.Command(app.Path)
-			err := cmd.Start()
-			if err != nil {
-				return nil, err
+			if _, err := p.launchApp(ctx, appID, app, ticket, runningProcs, waitCheck, elapsed); err != nil {
+				fail(app, err)
 			}
-			newProc.pid = cmd.Process.Pid
-			p.logger.Info("Starting app", "path", newProc.path, "pid", newProc.pid)
+		}(appID, app, ticket)
+	}
+	wg.Wait()
+
+	if startErr != nil {
+		return &StartupError{Err: startErr}
+	}
+	p.setState(StateRunning)
+	return nil
+}
+
+// launchApp launches the app once its start delay is elapsed and the launch
+// pacer allows it, then waits for it to be ready. The processes are tracked
+// as soon as they are started so that they can be stopped, the ones which were
+// not tracked yet are returned, even on failure.
+func (p *ProcessHander) launchApp(ctx context.Context, appID int, app AppConfig, ticket *launchTicket, runningProcs []ps.Process, waitCheck time.Duration, elapsed func() string) ([]ProcessDetails, error) {
+	defer func() { ticket.release() }()
+	if app.StartDelay > 0 {
+		p.logger.Info("Delaying app start", "app", app.DisplayName(), "delay", app.StartDelay.String(), "elapsed", elapsed())
+		if err := sleepContext(ctx, time.Duration(app.StartDelay)); err != nil {
+			return nil, err
+		}
+	}
+	if ticket == nil {
+		ticket = p.pacer.reserve()
+	}
+	if err := ticket.acquire(ctx); err != nil {
+		return nil, err
+	}
+
+	// the readiness probe captures the state of its files before the launch
+	var readiness *probe
+	if app.Readiness != nil {
+		pr, err := newProbe(*app.Readiness)
+		if err != nil {
+			return nil, err
 		}
-		procs[newProc.pid] = newProc
+		readiness = pr
 	}
 
-	return procs, nil
+	p.logger.Info("Launching app", "app", app.DisplayName(), "elapsed", elapsed())
+	newProcs, err := p.startApp(appID, app, runningProcs)
+	if err != nil {
+		p.logger.Error("Cannot start app", "app", app.DisplayName(), "error", err)
+		return nil, err
+	}
+	var added []ProcessDetails
+	for _, newProc := range newProcs {
+		if p.addProcess(newProc) {
+			added = append(added, newProc)
+		}
+	}
+	newProc := newProcs[0]
+
+	if !newProc.launched() && readiness != nil {
+		readiness.reuse()
+	}
+
+	if readiness == nil {
+		if err := sleepContext(ctx, waitCheck); err != nil {
+			return added, err
+		}
+	} else if err := p.waitReady(ctx, newProc, readiness); err != nil {
+		p.logger.Error("App is not ready", "app", app.DisplayName(), "error", err)
+		return added, err
+	}
+	p.logger.Info("App is ready", "app", app.DisplayName(), "pid", newProc.pid, "elapsed", elapsed())
+	return added, nil
 }
 
-func (p *ProcessHander) findPidFromPath(path string, procs []ps.Process) (int, error) {
-	for _, proc := range procs {
-		if procPath, _ := proc.Path(); procPath == path {
-			p.logger.Debug("Found running app", "path", path, "pid", proc.Pid())
-			return proc.Pid(), nil
+// startApp looks for the existing instances of the app if allowed, otherwise starts it
+func (p *ProcessHander) startApp(appID int, app AppConfig, runningProcs []ps.Process) ([]ProcessDetails, error) {
+	newProc := ProcessDetails{pid: -1}
+	newProc.setApp(appID, app)
+
+	envVars, err := appEnvVars(app)
+	if err != nil {
+		return nil, err
+	}
+
+	if app.UseExistingInstance {
+		// check existing processes
+		pids, err := p.adoptedPids(app, envVars, runningProcs)
+		if err != nil {
+			return nil, err
 		}
+		if len(pids) > 0 {
+			var procs []ProcessDetails
+			for _, pid := range pids {
+				adopted := newProc
+				adopted.pid = pid
+				adopted.watcher, err = newAdoptedWatcher(pid)
+				if err != nil {
+					p.logger.Warn("Cannot watch running app", "path", adopted.path, "pid", pid, "error", err)
+					adopted.watcher = newPollWatcher(pid)
+				}
+				p.logger.Info("Found running app", "app", app.DisplayName(), "path", adopted.path, "pid", pid)
+				adopted.watch()
+				procs = append(procs, adopted)
+			}
+			return procs, nil
+		}
+	}
+
+	// start app if not found in existing processes
+	cmd := exec.Command(app.Path, app.Args...)
+	cmd.Dir = app.WorkingDir
+	cmd.Env = buildEnv(app, envVars)
+	setProcessGroup(cmd)
+
+	stdout, stderr, closers, err := p.outputWriters(app)
+	if err != nil {
+		return nil, err
 	}
-	return -1, nil
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	cmd.WaitDelay = outputWaitDelay
+
+	if err := cmd.Start(); err != nil {
+		closeAll(closers)
+		return nil, err
+	}
+	newProc.pid = cmd.Process.Pid
+	newProc.group = runtime.GOOS != "windows"
+	newProc.watcher = &childWatcher{cmd: cmd, closers: closers}
+	p.logger.Info("Starting app", "app", app.DisplayName(), "path", newProc.path, "pid", newProc.pid)
+	newProc.watch()
+
+	return []ProcessDetails{newProc}, nil
+}
+
+// setApp sets the app of the process and the settings derived from its config
+func (proc *ProcessDetails) setApp(appID int, app AppConfig) {
+	proc.appID = appID
+	proc.app = app
+	proc.path = app.Path
+	proc.killOnExit = app.KillOnExit
+	proc.stopSignal = app.StopSignal
+	proc.stopTimeout = durationOrDefault(app.StopTimeout, defaultStopTimeout)
+	if proc.stopSignal == "" {
+		proc.stopSignal = defaultStopSignal
+	}
+}
+
+// launched tells if the process was started by runsyncapps, as opposed to
+// an existing instance being reused
+func (proc ProcessDetails) launched() bool {
+	_, launched := proc.watcher.(*childWatcher)
+	return launched
+}
+
+// watch starts waiting for the process to exit right away,
+// so that children are reaped as soon as they exit
+func (proc *ProcessDetails) watch() {
+	proc.exited = make(chan struct{})
+	proc.exit = &processExit{}
+	go func(watcher processWatcher, exited chan struct{}, exit *processExit) {
+		*exit = watcher.Wait()
+		close(exited)
+	}(proc.watcher, proc.exited, proc.exit)
 }
 
 // check if a process is running
@@ -80,68 +317,358 @@ func (p *ProcessHander) isProcessRunning(pid int) bool {
 	return process != nil && err == nil
 }
 
-func (p *ProcessHander) CheckRunningProcesses(procs map[int]ProcessDetails) {
-	chanProcesses := make(chan int)
-	for pid := range procs {
-		go p.checkRunningProcess(pid, chanProcesses)
+// check if a process or one of the members of its process group is running
+func (p *ProcessHander) isProcessAlive(proc ProcessDetails) bool {
+	running := p.isProcessRunning(proc.pid)
+	if proc.exited != nil {
+		select {
+		case <-proc.exited:
+			running = false
+		default:
+		}
 	}
-	closedProcess := <-chanProcesses
-	p.logger.Info("Process closed", "path", procs[closedProcess].path, "pid", closedProcess)
+	return running || (proc.group && processGroupAlive(proc.pid))
 }
 
-func (p *ProcessHander) checkRunningProcess(pid int, processes chan int) {
-	process, err := os.FindProcess(pid)
+// processTree returns the descendants of a process using their parent pid
+func processTree(pid int) []int {
+	procs, err := ps.Processes()
 	if err != nil {
-		processes <- pid
+		return nil
+	}
+
+	children := make(map[int][]int)
+	for _, proc := range procs {
+		if proc.Pid() != proc.PPid() {
+			children[proc.PPid()] = append(children[proc.PPid()], proc.Pid())
+		}
+	}
+
+	var tree []int
+	visited := map[int]bool{pid: true}
+	queue := []int{pid}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, child := range children[current] {
+			if !visited[child] {
+				visited[child] = true
+				tree = append(tree, child)
+				queue = append(queue, child)
+			}
+		}
+	}
+	return tree
+}
+
+// CheckRunningProcesses waits for the closed apps to fire the trigger, the
+// apps exiting in the meantime are restarted or ignored according to their
+// exit rules, restart policy and critical flag. It returns an AppExitError
+// when the group is stopped because of an app failure and nil when the
+// context is cancelled.
+func (p *ProcessHander) CheckRunningProcesses(ctx context.Context, trigger *TriggerConfig) error {
+	done := make(chan struct{})
+	defer close(done)
+
+	chanProcesses := make(chan processExit)
+	chanRestarts := make(chan ProcessDetails)
+	chanStarted := make(chan appStart)
+
+	// the apps started on reload stop waiting once the monitoring ends
+	launchCtx, cancelLaunches := context.WithCancel(ctx)
+	defer cancelLaunches()
+	apps := make(map[int]bool)
+	for _, proc := range p.processes() {
+		apps[proc.appID] = true
+		go p.checkRunningProcess(proc, chanProcesses, done)
+	}
+	groupTrigger := newGroupTrigger(trigger, len(apps))
+
+	pendingRestarts := 0
+	for p.processCount() > 0 || pendingRestarts > 0 {
+		select {
+		case <-ctx.Done():
+			p.logger.Info("Stop requested", "cause", context.Cause(ctx))
+			return nil
+
+		case exit := <-chanProcesses:
+			proc, found := p.removeProcess(exit.pid)
+			if !found {
+				// stopped after a config reload
+				continue
+			}
+			p.logger.Info("Process closed", "path", proc.path, "pid", exit.pid)
+
+			if strings.ToLower(proc.app.ClosedWhen) == ClosedWhenAll {
+				if remaining := p.instanceCount(proc.appID); remaining > 0 {
+					p.logger.Info("App instance closed, waiting for the other instances", "app", proc.app.DisplayName(), "remaining", remaining)
+					continue
+				}
+			}
+
+			switch p.exitAction(&proc, exit) {
+			case ExitActionRestart:
+				delay, restart := p.restartDelay(&proc)
+				if restart {
+					pendingRestarts++
+					go p.scheduleRestart(proc, delay, chanRestarts, done)
+					continue
+				}
+				if proc.app.IsCritical() && groupTrigger.close(proc) {
+					return appExitError(proc, exit)
+				}
+			case ExitActionStop:
+				if groupTrigger.close(proc) {
+					return appExitError(proc, exit)
+				}
+			}
+			p.logger.Info("App closed, keeping the other apps running", "path", proc.path)
+
+		case <-p.updated:
+			pendingRestarts += p.applyUpdate(ctx, launchCtx, groupTrigger, chanStarted, done)
+
+		case start := <-chanStarted:
+			pendingRestarts--
+			for _, proc := range start.procs {
+				go p.checkRunningProcess(proc, chanProcesses, done)
+			}
+			if start.err != nil && start.proc.app.IsCritical() && groupTrigger.close(start.proc) {
+				return &AppExitError{App: start.proc.app.DisplayName(), ExitCode: -1, Err: start.err}
+			}
+
+		case proc := <-chanRestarts:
+			pendingRestarts--
+			appID, app, found := p.currentApp(proc.app)
+			if !found {
+				p.logger.Info("App removed from the config, not restarting it", "app", proc.app.DisplayName())
+				continue
+			}
+			proc.setApp(appID, app)
+			runningProcs, err := ps.Processes()
+			if err != nil {
+				p.logger.Warn("Error listing processed", "error", err)
+			}
+			newProcs, err := p.startApp(proc.appID, proc.app, runningProcs)
+			if err != nil {
+				p.logger.Error("Cannot restart app", "path", proc.path, "error", err)
+				if proc.app.IsCritical() && groupTrigger.close(proc) {
+					return &AppExitError{App: proc.app.DisplayName(), ExitCode: -1, Err: err}
+				}
+				continue
+			}
+			for _, newProc := range newProcs {
+				// the other adopted instances are already monitored
+				newProc.restarts = slices.Clone(proc.restarts)
+				if p.addProcess(newProc) {
+					go p.checkRunningProcess(newProc, chanProcesses, done)
+				}
+			}
+		}
+	}
+	p.logger.Info("No app left running")
+	return nil
+}
+
+// exitAction decides what to do when a process exits: the first matching exit
+// rule wins, otherwise the app is restarted according to its restart policy
+// and the group is stopped if the app is critical
+func (p *ProcessHander) exitAction(proc *ProcessDetails, exit processExit) string {
+	if action, found := exitAction(proc.app.OnExit, exit); found {
+		p.logger.Debug("Applying exit rule", "app", proc.app.DisplayName(), "action", action)
+		return action
+	}
+	if p.shouldRestart(*proc, exit) {
+		return ExitActionRestart
+	}
+	if proc.app.IsCritical() {
+		return ExitActionStop
+	}
+	return ExitActionIgnore
+}
+
+// appExitError returns the error describing the exit of the app stopping the group,
+// nil if it exited successfully
+func appExitError(proc ProcessDetails, exit processExit) error {
+	if !exit.failed() {
+		return nil
+	}
+	return &AppExitError{App: proc.app.DisplayName(), ExitCode: exit.exitCode, Signal: exit.signal, Err: exit.err}
+}
+
+func (p *ProcessHander) checkRunningProcess(proc ProcessDetails, processes chan processExit, done chan struct{}) {
+	// a hung app failing its liveness probe is handled as an exited one
+	unhealthy := make(chan error, 1)
+	if proc.app.Liveness != nil {
+		go func() {
+			if err := p.watchLiveness(proc, *proc.app.Liveness, done); err != nil {
+				unhealthy <- err
+			}
+		}()
 	}
 
 	// Wait the process to exit
-	p.logger.Debug("Waiting process to exit", "pid", pid)
-	processState, err := process.Wait()
-	if err != nil {
-		p.logger.Warn("Error while waiting process", "pid", pid)
-		processes <- pid
+	p.logger.Debug("Waiting process to exit", "pid", proc.pid)
+	var exit processExit
+	select {
+	case <-proc.exited:
+		exit = *proc.exit
+	case err := <-unhealthy:
+		p.logger.Warn("App is not responding, killing it", "app", proc.app.DisplayName(), "pid", proc.pid, "error", err)
+		if _, errKill := p.killProcess(proc); errKill != nil {
+			p.logger.Warn("Error when killing process", "path", proc.path, "pid", proc.pid, "error", errKill)
+		}
+		select {
+		case <-proc.exited:
+			exit = *proc.exit
+		case <-time.After(proc.stopTimeout):
+			exit = processExit{pid: proc.pid, exitCode: -1}
+		}
+		exit.err = err
+		exit.unhealthy = true
+	case <-done:
 		return
 	}
 
-	if processState.Exited() {
-		p.logger.Debug("Process exited", "pid", pid, "exitcode", processState.ExitCode())
-		processes <- pid
-		return
+	switch {
+	case exit.unhealthy:
+		p.logger.Debug("Process killed after failing its liveness probe", "pid", proc.pid)
+	case exit.err != nil:
+		// something went wrong, let's assume process is over
+		p.logger.Warn("Error while waiting process", "pid", proc.pid, "error", exit.err)
+	case exit.signal != "":
+		p.logger.Debug("Process exited", "pid", proc.pid, "signal", exit.signal)
+	default:
+		p.logger.Debug("Process exited", "pid", proc.pid, "exitcode", exit.exitCode)
 	}
 
-	// something went wrong (?), let's assume process is over
-	p.logger.Warn("Process exited with issue", "pid", pid, "state", processState)
-	processes <- pid
+	select {
+	case processes <- exit:
+	case <-done:
+	}
 }
 
-func (p *ProcessHander) KillProcesses(procs map[int]ProcessDetails) {
+// KillProcesses stops concurrently the apps flagged with killOnExit:
+// each app gets the grace period to exit by itself, then its stop signal
+// is sent and if it is still running after its stop timeout it gets killed.
+// An app is stopped only once the apps depending on it are stopped.
+// It returns once all the apps are stopped, when the context is cancelled
+// the remaining apps are killed right away.
+func (p *ProcessHander) KillProcesses(ctx context.Context, grace time.Duration) {
+	p.setState(StateStopping)
+	defer p.setState(StateIdle)
+
 	p.logger.Info("Killing other apps")
+	graceEnd := time.Now().Add(grace)
+	procs := p.processes()
+
+	// a channel per named app, closed once all its instances are stopped
+	apps := make([]AppConfig, 0, len(procs))
+	stopped := make(map[string]chan struct{})
+	instances := make(map[string]*sync.WaitGroup)
 	for _, proc := range procs {
-		if proc.killOnExit {
-			p.logger.Debug("Killing process", "path", proc.path, "pid", proc.pid)
-			procKilled, err := p.killProcess(proc.pid)
-			if err != nil {
-				p.logger.Warn("Error when killing process", "path", proc.path, "pid", proc.pid, "error", err)
+		apps = append(apps, proc.app)
+		if proc.app.Name == "" {
+			continue
+		}
+		if _, found := stopped[proc.app.Name]; !found {
+			stopped[proc.app.Name] = make(chan struct{})
+			instances[proc.app.Name] = &sync.WaitGroup{}
+		}
+		instances[proc.app.Name].Add(1)
+	}
+	for name, wg := range instances {
+		go func(ch chan struct{}, wg *sync.WaitGroup) {
+			wg.Wait()
+			close(ch)
+		}(stopped[name], wg)
+	}
+	dependentApps := dependents(apps)
+
+	var wg sync.WaitGroup
+	for _, proc := range procs {
+		wg.Add(1)
+		go func(proc ProcessDetails) {
+			defer wg.Done()
+			if proc.app.Name != "" {
+				defer instances[proc.app.Name].Done()
+			}
+
+			if !proc.killOnExit {
+				p.logger.Debug("Skipping process", "path", proc.path, "pid", proc.pid)
+				return
 			}
-			if procKilled {
-				p.logger.Info("Killed process", "path", proc.path, "pid", proc.pid)
+
+			for _, dependent := range dependentApps[proc.app.Name] {
+				if ch, found := stopped[dependent]; found {
+					p.logger.Debug("Waiting for dependent app to stop", "app", proc.app.DisplayName(), "dependent", dependent)
+					select {
+					case <-ch:
+					case <-ctx.Done():
+					}
+				}
 			}
+			p.stopProcess(ctx, proc, time.Until(graceEnd))
+		}(proc)
+	}
+	wg.Wait()
+
+	p.mu.Lock()
+	p.procs = make(map[int]ProcessDetails)
+	p.mu.Unlock()
+}
+
+func (p *ProcessHander) stopProcess(ctx context.Context, proc ProcessDetails, grace time.Duration) {
+	if p.waitProcessExit(ctx, proc, grace) {
+		p.logger.Debug("Process already exited", "path", proc.path, "pid", proc.pid)
+		return
+	}
+
+	if strings.TrimPrefix(strings.ToUpper(proc.stopSignal), "SIG") != defaultStopSignal && ctx.Err() == nil {
+		p.logger.Debug("Stopping process", "path", proc.path, "pid", proc.pid, "signal", proc.stopSignal)
+		if err := sendStopSignal(proc.pid, proc.group, proc.stopSignal); err != nil {
+			p.logger.Warn("Error when stopping process", "path", proc.path, "pid", proc.pid, "signal", proc.stopSignal, "error", err)
+		} else if p.waitProcessExit(ctx, proc, proc.stopTimeout) {
+			p.logger.Info("Stopped process", "path", proc.path, "pid", proc.pid, "signal", proc.stopSignal)
+			return
 		} else {
-			p.logger.Debug("Skipping process", "path", proc.path, "pid", proc.pid)
+			p.logger.Warn("Process still running after stop timeout", "path", proc.path, "pid", proc.pid, "timeout", proc.stopTimeout.String())
+		}
+	}
+
+	p.logger.Debug("Killing process", "path", proc.path, "pid", proc.pid)
+	procKilled, err := p.killProcess(proc)
+	if err != nil {
+		p.logger.Warn("Error when killing process", "path", proc.path, "pid", proc.pid, "error", err)
+	}
+	if procKilled {
+		p.logger.Info("Killed process", "path", proc.path, "pid", proc.pid)
+	}
+}
+
+// wait for a process tree to exit, returns false if it is still running
+// after the timeout or when the context is cancelled
+func (p *ProcessHander) waitProcessExit(ctx context.Context, proc ProcessDetails, timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for p.isProcessAlive(proc) {
+		if !time.Now().Before(deadline) || ctx.Err() != nil {
+			return false
+		}
+		select {
+		case <-time.After(exitPollInterval):
+		case <-ctx.Done():
 		}
 	}
+	return true
 }
 
-func (p *ProcessHander) killProcess(pid int) (bool, error) {
-	if !p.isProcessRunning(pid) {
+func (p *ProcessHander) killProcess(proc ProcessDetails) (bool, error) {
+	if !p.isProcessAlive(proc) {
 		return false, nil
 	}
 
-	process, _ := os.FindProcess(pid)
-	err := process.Kill()
+	err := killProcessTree(proc.pid, proc.group)
 	if err != nil {
-		p.logger.Warn("Cannot kill process", "pid", pid)
+		p.logger.Warn("Cannot kill process", "pid", proc.pid)
 		return false, err
 	}
 	return true, nil
@@ -0,0 +1,16 @@
+package internal
+
+import "errors"
+
+// errUnsupported is returned when a process attribute
+// cannot be read on the current platform
+var errUnsupported = errors.New("not supported on this platform")
+
+// processArgs returns the arguments of a running process (without the executable)
+func processArgs(pid int) ([]string, error) {
+	cmdLine, err := processCommandLine(pid)
+	if err != nil || len(cmdLine) == 0 {
+		return nil, err
+	}
+	return cmdLine[1:], nil
+}
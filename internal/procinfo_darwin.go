@@ -0,0 +1,90 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"os/user"
+	"strconv"
+
+	"golang.org/x/sys/unix"
+)
+
+// processCommandLine returns the command line of a running process
+func processCommandLine(pid int) ([]string, error) {
+	args, _, err := readProcArgs(pid)
+	return args, err
+}
+
+// processUser returns the name of the user owning a running process
+func processUser(pid int) (string, error) {
+	info, err := unix.SysctlKinfoProc("kern.proc.pid", pid)
+	if err != nil {
+		return "", err
+	}
+	id := strconv.FormatUint(uint64(info.Eproc.Ucred.Uid), 10)
+	if u, err := user.LookupId(id); err == nil {
+		return u.Username, nil
+	}
+	return id, nil
+}
+
+// processWorkingDir returns the current directory of a running process,
+// it would require proc_pidinfo which is not exposed without cgo
+func processWorkingDir(pid int) (string, error) {
+	return "", errUnsupported
+}
+
+// processEnv returns the environment of a running process
+func processEnv(pid int) (map[string]string, error) {
+	_, env, err := readProcArgs(pid)
+	if err != nil {
+		return nil, err
+	}
+	return parseEnv(env), nil
+}
+
+// readProcArgs parses the kern.procargs2 sysctl which is laid out as:
+// argc, executable path, padding, argv[0..argc-1], env strings
+func readProcArgs(pid int) ([]string, []string, error) {
+	data, err := unix.SysctlRaw("kern.procargs2", pid)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(data) < 4 {
+		return nil, nil, errors.New("invalid procargs2 content")
+	}
+	argc := int(binary.LittleEndian.Uint32(data[:4]))
+	data = data[4:]
+
+	// skip the executable path and its padding
+	end := bytes.IndexByte(data, 0)
+	if end < 0 {
+		return nil, nil, errors.New("invalid procargs2 content")
+	}
+	data = bytes.TrimLeft(data[end:], "\x00")
+
+	var args, env []string
+	for len(data) > 0 {
+		end = bytes.IndexByte(data, 0)
+		if end < 0 {
+			end = len(data)
+		}
+		if end == 0 {
+			// an empty string ends the env block
+			if len(args) == argc {
+				break
+			}
+		}
+		if len(args) < argc {
+			args = append(args, string(data[:end]))
+		} else {
+			env = append(env, string(data[:end]))
+		}
+		if end == len(data) {
+			break
+		}
+		data = data[end+1:]
+	}
+	return args, env, nil
+}
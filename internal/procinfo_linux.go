@@ -0,0 +1,68 @@
+package internal
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/user"
+	"strconv"
+	"syscall"
+)
+
+// processCommandLine returns the command line of a running process
+func processCommandLine(pid int) ([]string, error) {
+	data, err := os.ReadFile(fmt.Sprintf("/proc/%d/cmdline", pid))
+	if err != nil {
+		return nil, err
+	}
+	return splitNull(data), nil
+}
+
+// processUser returns the name of the user owning a running process
+func processUser(pid int) (string, error) {
+	info, err := os.Stat(fmt.Sprintf("/proc/%d", pid))
+	if err != nil {
+		return "", err
+	}
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return "", errUnsupported
+	}
+	return lookupUid(stat.Uid), nil
+}
+
+// lookupUid returns the name of a user, or its id if it is unknown
+func lookupUid(uid uint32) string {
+	id := strconv.FormatUint(uint64(uid), 10)
+	if u, err := user.LookupId(id); err == nil {
+		return u.Username
+	}
+	return id
+}
+
+// processWorkingDir returns the current directory of a running process
+func processWorkingDir(pid int) (string, error) {
+	return os.Readlink(fmt.Sprintf("/proc/%d/cwd", pid))
+}
+
+// processEnv returns the environment of a running process
+func processEnv(pid int) (map[string]string, error) {
+	data, err := os.ReadFile(fmt.Sprintf("/proc/%d/environ", pid))
+	if err != nil {
+		return nil, err
+	}
+	return parseEnv(splitNull(data)), nil
+}
+
+func splitNull(data []byte) []string {
+	data = bytes.TrimRight(data, "\x00")
+	if len(data) == 0 {
+		return nil
+	}
+	parts := bytes.Split(data, []byte{0})
+	res := make([]string, len(parts))
+	for i, p := range parts {
+		res[i] = string(p)
+	}
+	return res
+}
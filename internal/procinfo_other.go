@@ -0,0 +1,23 @@
+//go:build !linux && !darwin && !windows
+
+package internal
+
+// processCommandLine returns the command line of a running process
+func processCommandLine(pid int) ([]string, error) {
+	return nil, errUnsupported
+}
+
+// processUser returns the name of the user owning a running process
+func processUser(pid int) (string, error) {
+	return "", errUnsupported
+}
+
+// processWorkingDir returns the current directory of a running process
+func processWorkingDir(pid int) (string, error) {
+	return "", errUnsupported
+}
+
+// processEnv returns the environment of a running process
+func processEnv(pid int) (map[string]string, error) {
+	return nil, errUnsupported
+}
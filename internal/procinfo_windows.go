@@ -0,0 +1,135 @@
+package internal
+
+import (
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// processCommandLine returns the command line of a running process
+func processCommandLine(pid int) ([]string, error) {
+	params, h, err := readProcessParameters(pid)
+	if err != nil {
+		return nil, err
+	}
+	defer windows.CloseHandle(h)
+
+	cmdLine, err := readUnicodeString(h, params.CommandLine)
+	if err != nil {
+		return nil, err
+	}
+	return windows.DecomposeCommandLine(cmdLine)
+}
+
+// processUser returns the name of the user owning a running process as DOMAIN\user
+func processUser(pid int) (string, error) {
+	h, err := windows.OpenProcess(windows.PROCESS_QUERY_LIMITED_INFORMATION, false, uint32(pid))
+	if err != nil {
+		return "", err
+	}
+	defer windows.CloseHandle(h)
+
+	var token windows.Token
+	if err := windows.OpenProcessToken(h, windows.TOKEN_QUERY, &token); err != nil {
+		return "", err
+	}
+	defer token.Close()
+
+	tokenUser, err := token.GetTokenUser()
+	if err != nil {
+		return "", err
+	}
+	account, domain, _, err := tokenUser.User.Sid.LookupAccount("")
+	if err != nil {
+		return tokenUser.User.Sid.String(), nil
+	}
+	return domain + `\` + account, nil
+}
+
+// processWorkingDir returns the current directory of a running process
+func processWorkingDir(pid int) (string, error) {
+	params, h, err := readProcessParameters(pid)
+	if err != nil {
+		return "", err
+	}
+	defer windows.CloseHandle(h)
+
+	return readUnicodeString(h, params.CurrentDirectory.DosPath)
+}
+
+// processEnv returns the environment of a running process
+func processEnv(pid int) (map[string]string, error) {
+	params, h, err := readProcessParameters(pid)
+	if err != nil {
+		return nil, err
+	}
+	defer windows.CloseHandle(h)
+
+	size := params.EnvironmentSize
+	if params.Environment == nil || size == 0 {
+		return map[string]string{}, nil
+	}
+	buf := make([]uint16, size/2)
+	if err := windows.ReadProcessMemory(h, uintptr(params.Environment), (*byte)(unsafe.Pointer(&buf[0])), size&^1, nil); err != nil {
+		return nil, err
+	}
+
+	// the block is a list of null terminated strings ending with an empty one
+	var env []string
+	start := 0
+	for i, c := range buf {
+		if c != 0 {
+			continue
+		}
+		if i == start {
+			break
+		}
+		env = append(env, windows.UTF16ToString(buf[start:i]))
+		start = i + 1
+	}
+	return parseEnv(env), nil
+}
+
+// readProcessParameters reads the process parameters from the PEB of a
+// process, the returned handle must be closed by the caller
+func readProcessParameters(pid int) (*windows.RTL_USER_PROCESS_PARAMETERS, windows.Handle, error) {
+	h, err := windows.OpenProcess(windows.PROCESS_QUERY_INFORMATION|windows.PROCESS_VM_READ, false, uint32(pid))
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var info windows.PROCESS_BASIC_INFORMATION
+	err = windows.NtQueryInformationProcess(h, windows.ProcessBasicInformation, unsafe.Pointer(&info), uint32(unsafe.Sizeof(info)), nil)
+	if err != nil {
+		windows.CloseHandle(h)
+		return nil, 0, err
+	}
+
+	var peb windows.PEB
+	err = windows.ReadProcessMemory(h, uintptr(unsafe.Pointer(info.PebBaseAddress)), (*byte)(unsafe.Pointer(&peb)), unsafe.Sizeof(peb), nil)
+	if err != nil {
+		windows.CloseHandle(h)
+		return nil, 0, err
+	}
+
+	var params windows.RTL_USER_PROCESS_PARAMETERS
+	err = windows.ReadProcessMemory(h, uintptr(unsafe.Pointer(peb.ProcessParameters)), (*byte)(unsafe.Pointer(&params)), unsafe.Sizeof(params), nil)
+	if err != nil {
+		windows.CloseHandle(h)
+		return nil, 0, err
+	}
+	return &params, h, nil
+}
+
+// readUnicodeString reads a string located in the memory of another process
+func readUnicodeString(h windows.Handle, s windows.NTUnicodeString) (string, error) {
+	if s.Length == 0 {
+		return "", nil
+	}
+	buf := make([]uint16, s.Length/2)
+	err := windows.ReadProcessMemory(h, uintptr(unsafe.Pointer(s.Buffer)), (*byte)(unsafe.Pointer(&buf[0])), uintptr(s.Length), nil)
+	if err != nil {
+		return "", err
+	}
+	return windows.UTF16ToString(buf), nil
+}
@@ -0,0 +1,121 @@
+package internal
+
+import (
+	"fmt"
+	"slices"
+)
+
+// ProfileConfig is a variant of the config: its fields override the config
+// ones the same way as a config layer and it enables or disables apps by name
+type ProfileConfig struct {
+	Enable       []string          `json:"enable"`
+	Disable      []string          `json:"disable"`
+	Vars         map[string]string `json:"vars"`
+	WaitCheck    Duration          `json:"waitCheck"`
+	WaitExit     Duration          `json:"waitExit"`
+	Trigger      *TriggerConfig    `json:"trigger"`
+	Applications []AppConfig       `json:"applications"`
+	Groups       []GroupConfig     `json:"groups"`
+
+	StaggerInterval   Duration `json:"staggerInterval"`
+	MaxParallelStarts int      `json:"maxParallelStarts"`
+}
+
+// ProfileNames returns the sorted names of the profiles of the config
+func (c *ConfigFile) ProfileNames() []string {
+	names := make([]string, 0, len(c.Profiles))
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// applyProfile merges the profile on top of the config document, then
+// enables and disables its apps and removes the disabled apps. The default
+// profile of the config is used when the profile is empty.
+func applyProfile(doc map[string]any, profile string, v *validator) error {
+	if profile == "" {
+		profile, _ = doc["defaultProfile"].(string)
+		if profile == "" {
+			removeDisabledApps(doc, v.positions)
+			return nil
+		}
+	}
+	profiles, _ := doc["profiles"].(map[string]any)
+	profileDoc, found := profiles[profile].(map[string]any)
+	if !found {
+		return fmt.Errorf("unknown profile %q", profile)
+	}
+
+	profilePath := childPath("profiles", profile)
+	overrides := make(map[string]any)
+	for key, value := range profileDoc {
+		if key != "enable" && key != "disable" {
+			overrides[key] = value
+		}
+	}
+	mergeDocuments(doc, overrides, "", profilePath, v.positions, v.positions)
+
+	for _, key := range []string{"enable", "disable"} {
+		names, _ := profileDoc[key].([]any)
+		for i, name := range names {
+			if !setDisabled(doc, name, key == "disable") {
+				v.addf(indexPath(childPath(profilePath, key), i), "unknown app %q", name)
+			}
+		}
+	}
+	removeDisabledApps(doc, v.positions)
+	return nil
+}
+
+// setDisabled sets the disabled flag of the apps with the given name,
+// it returns false if there is no such app
+func setDisabled(doc map[string]any, name any, disabled bool) bool {
+	found := false
+	forEachAppList(doc, "", func(apps []any, path string) []any {
+		for _, app := range apps {
+			if app, ok := app.(map[string]any); ok && app["name"] == name {
+				app["disabled"] = disabled
+				found = true
+			}
+		}
+		return apps
+	})
+	return found
+}
+
+// removeDisabledApps removes the disabled apps from the config document,
+// the positions of the following apps are shifted accordingly
+func removeDisabledApps(doc map[string]any, pos positions) {
+	forEachAppList(doc, "", func(apps []any, path string) []any {
+		for i := len(apps) - 1; i >= 0; i-- {
+			if app, ok := apps[i].(map[string]any); !ok || app["disabled"] != true {
+				continue
+			}
+			for j := i + 1; j < len(apps); j++ {
+				shifted := make(positions)
+				shifted.move(indexPath(path, j-1), pos, indexPath(path, j))
+				pos.move(indexPath(path, j-1), shifted, indexPath(path, j-1))
+			}
+			pos.move(indexPath(path, len(apps)-1), nil, "")
+			apps = slices.Delete(apps, i, i+1)
+		}
+		return apps
+	})
+}
+
+// forEachAppList calls fn with the top-level applications and the ones of
+// each group, the lists are replaced by the ones returned
+func forEachAppList(doc map[string]any, path string, fn func(apps []any, path string) []any) {
+	if apps, ok := doc["applications"].([]any); ok {
+		doc["applications"] = fn(apps, childPath(path, "applications"))
+	}
+	if groups, ok := doc["groups"].([]any); ok {
+		for i, group := range groups {
+			if group, ok := group.(map[string]any); ok {
+				forEachAppList(group, indexPath(childPath(path, "groups"), i), fn)
+			}
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package internal
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+const profileConfig = `defaultProfile: light
+groups:
+  - name: g1
+    applications:
+      - name: a
+        path: $EXE
+        disabled: true
+      - name: b
+        path: $EXE
+      - name: c
+        path: $EXE
+  - name: g2
+    applications:
+      - name: d
+        path: $EXE
+        disabled: true
+      - name: e
+        path: $EXE
+profiles:
+  light:
+    disable: [b]
+  full:
+    enable: [a, d]
+  broken:
+    enable: [z]
+    disable: [b, y]
+`
+
+func TestApplyProfile(t *testing.T) {
+	tests := []struct {
+		profile string
+		apps    [][]string
+	}{
+		{profile: "", apps: [][]string{{"c"}, {"e"}}},
+		{profile: "light", apps: [][]string{{"c"}, {"e"}}},
+		{profile: "full", apps: [][]string{{"a", "b", "c"}, {"d", "e"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(cmp.Or(tt.profile, "default"), func(t *testing.T) {
+			file := writeConfig(t, t.TempDir(), "config.yaml", profileConfig)
+			config, err := LoadConfigFiles([]string{file}, "", tt.profile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var apps [][]string
+			for _, group := range config.AppGroups() {
+				apps = append(apps, appNames(group.Applications))
+			}
+			if !slices.EqualFunc(apps, tt.apps, slices.Equal) {
+				t.Errorf("apps = %q, want %q", apps, tt.apps)
+			}
+		})
+	}
+
+	file := writeConfig(t, t.TempDir(), "config.yaml", profileConfig)
+	if _, err := LoadConfigFiles([]string{file}, "", "other"); err == nil {
+		t.Error("unknown profile accepted")
+	}
+}
+
+// the problems of the apps following a disabled one are reported at their
+// position in the file
+const profileProblemsConfig = `groups:
+  - applications:
+      - name: a
+        path: $EXE
+        stopSignal: SIGFOO
+        disabled: true
+      - name: b
+        path: $EXE
+      - name: c
+        path: $EXE
+        stopSignal: SIGBAR
+      - name: d
+        path: $EXE
+        restart: sometimes
+  - applications:
+      - name: e
+        path: $EXE
+        disabled: true
+      - name: f
+        dependsOn: [e]
+profiles:
+  light:
+    disable: [b]
+  full:
+    enable: [a, e]
+  broken:
+    enable: [z]
+    disable: [b, y]
+`
+
+func TestApplyProfileProblems(t *testing.T) {
+	const (
+		signalA  = `config.yaml:5: groups[0].applications[0].stopSignal: unknown signal "SIGFOO", expected one of HUP, INT, QUIT, TERM, USR1, USR2, KILL`
+		signalC  = `config.yaml:11: groups[0].applications[2].stopSignal: unknown signal "SIGBAR", expected one of HUP, INT, QUIT, TERM, USR1, USR2, KILL`
+		restartD = `config.yaml:14: groups[0].applications[3].restart: invalid value "sometimes", expected one of never, on-failure, always`
+		pathF    = `config.yaml:19: groups[1].applications[1].path: missing path`
+		depF     = `config.yaml:20: groups[1].applications[1].dependsOn[0]: app "f" depends on unknown app "e"`
+	)
+	tests := []struct {
+		profile  string
+		problems []string // "file:line: path: message"
+	}{
+		{
+			profile:  "",
+			problems: []string{signalC, restartD, pathF, depF},
+		},
+		{
+			profile:  "light",
+			problems: []string{signalC, restartD, pathF, depF},
+		},
+		{
+			profile:  "full",
+			problems: []string{signalA, signalC, restartD, pathF},
+		},
+		{
+			profile: "broken",
+			problems: []string{
+				signalC,
+				restartD,
+				pathF,
+				depF,
+				`config.yaml:27: profiles.broken.enable[0]: unknown app "z"`,
+				`config.yaml:28: profiles.broken.disable[1]: unknown app "y"`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(cmp.Or(tt.profile, "default"), func(t *testing.T) {
+			file := writeConfig(t, t.TempDir(), "config.yaml", profileProblemsConfig)
+			_, err := LoadConfigFiles([]string{file}, "", tt.profile)
+
+			var configErr *ConfigError
+			if !errors.As(err, &configErr) {
+				t.Fatalf("LoadConfigFiles() error = %v, want a ConfigError", err)
+			}
+			var problems []string
+			for _, problem := range configErr.Problems {
+				problems = append(problems, fmt.Sprintf("%s:%d: %s: %s", filepath.Base(problem.File), problem.Line, problem.Path, problem.Message))
+			}
+			if !slices.Equal(problems, tt.problems) {
+				t.Errorf("problems =\n%s\nwant\n%s", strings.Join(problems, "\n"), strings.Join(tt.problems, "\n"))
+			}
+		})
+	}
+}
@@ -0,0 +1,262 @@
+package internal
+
+import (
+	"context"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	ps "github.com/keybase/go-ps"
+)
+
+// appsDiff is the difference between the apps of a group before and after a
+// config reload, the changed apps are the ones whose launch parameters differ
+type appsDiff struct {
+	added     []AppConfig
+	removed   []AppConfig
+	changed   []AppConfig
+	unchanged []AppConfig
+}
+
+// appKey identifies an app across config reloads: by name, or by path and
+// arguments for the unnamed apps
+func appKey(app AppConfig) string {
+	if app.Name != "" {
+		return app.Name
+	}
+	return strings.Join(append([]string{app.Path}, app.Args...), "\x00")
+}
+
+// launchParams returns the settings used to launch the app, the app is
+// restarted on reload when one of them changes
+func launchParams(app AppConfig) AppConfig {
+	return AppConfig{
+		Path:                app.Path,
+		Args:                app.Args,
+		Env:                 app.Env,
+		ReplaceEnv:          app.ReplaceEnv,
+		EnvFile:             app.EnvFile,
+		WorkingDir:          app.WorkingDir,
+		UseExistingInstance: app.UseExistingInstance,
+		Match:               app.Match,
+		Adopt:               app.Adopt,
+		Stdout:              app.Stdout,
+		Stderr:              app.Stderr,
+		LogMaxSize:          app.LogMaxSize,
+		LogMaxFiles:         app.LogMaxFiles,
+	}
+}
+
+func diffApps(old []AppConfig, new []AppConfig) appsDiff {
+	previous := make(map[string]AppConfig, len(old))
+	for _, app := range old {
+		previous[appKey(app)] = app
+	}
+
+	var diff appsDiff
+	for _, app := range new {
+		key := appKey(app)
+		oldApp, found := previous[key]
+		delete(previous, key)
+		switch {
+		case !found:
+			diff.added = append(diff.added, app)
+		case !reflect.DeepEqual(launchParams(oldApp), launchParams(app)):
+			diff.changed = append(diff.changed, app)
+		default:
+			diff.unchanged = append(diff.unchanged, app)
+		}
+	}
+	for _, app := range old {
+		if _, found := previous[appKey(app)]; found {
+			diff.removed = append(diff.removed, app)
+		}
+	}
+	return diff
+}
+
+func appNames(apps []AppConfig) []string {
+	names := make([]string, len(apps))
+	for i, app := range apps {
+		names[i] = app.DisplayName()
+	}
+	return names
+}
+
+// UpdateApps replaces the apps of the group with the ones of a reloaded
+// config. While the apps are monitored, the added apps are started, the
+// removed ones are stopped, the ones whose launch parameters changed are
+// restarted and the other ones are left running. Otherwise, the new apps
+// are used when the group is started again.
+func (p *ProcessHander) UpdateApps(apps []AppConfig) {
+	p.mu.Lock()
+	diff := diffApps(p.apps, apps)
+	p.update = apps
+	p.mu.Unlock()
+
+	p.logger.Info("Config reloaded",
+		"added", appNames(diff.added),
+		"removed", appNames(diff.removed),
+		"changed", appNames(diff.changed),
+		"unchanged", len(diff.unchanged))
+	select {
+	case p.updated <- struct{}{}:
+	default:
+		// an update is already pending
+	}
+}
+
+// appStart is the result of the start of an app added or changed by a
+// config reload
+type appStart struct {
+	proc  ProcessDetails   // app to start
+	procs []ProcessDetails // processes tracked by the start, even on failure
+	err   error
+}
+
+// applyUpdate applies the pending config reload to the monitored apps. The
+// apps to start are started once the previous instances are stopped, like at
+// startup: after the apps they depend on, once their start delay is elapsed
+// and the launch pacer allows it, and until they are ready. Their result is
+// sent to the started channel. It returns the number of apps to start.
+func (p *ProcessHander) applyUpdate(ctx context.Context, launchCtx context.Context, trigger *groupTrigger, started chan appStart, done chan struct{}) int {
+	p.mu.Lock()
+	if p.update == nil {
+		p.mu.Unlock()
+		return 0
+	}
+	oldApps, apps := p.apps, p.update
+	p.apps, p.update = apps, nil
+	waitCheck := p.waitCheck
+	diff := diffApps(oldApps, apps)
+
+	appIDs := make(map[string]int, len(apps))
+	for appID, app := range apps {
+		appIDs[appKey(app)] = appID
+	}
+	restarted := make(map[string]bool, len(diff.changed))
+	for _, app := range diff.changed {
+		restarted[appKey(app)] = true
+	}
+
+	// the processes to stop are no longer tracked so that their exit is ignored
+	stopping := make(map[string][]ProcessDetails)
+	for pid, proc := range p.procs {
+		key := appKey(proc.app)
+		appID, found := appIDs[key]
+		if !found || restarted[key] {
+			stopping[key] = append(stopping[key], proc)
+			delete(p.procs, pid)
+			continue
+		}
+		proc.setApp(appID, apps[appID])
+		p.procs[pid] = proc
+	}
+	p.mu.Unlock()
+
+	newIDs := make(map[int]int, len(oldApps))
+	for oldID, app := range oldApps {
+		if appID, found := appIDs[appKey(app)]; found {
+			newIDs[oldID] = appID
+		}
+	}
+	trigger.update(newIDs, len(apps))
+
+	for _, app := range diff.removed {
+		for _, proc := range stopping[appKey(app)] {
+			if !stoppedOnReload(proc) {
+				p.logger.Info("App removed from the config, leaving it running", "app", app.DisplayName(), "pid", proc.pid)
+				continue
+			}
+			p.logger.Info("App removed from the config, stopping it", "app", app.DisplayName(), "pid", proc.pid)
+			go p.stopProcess(ctx, proc, 0)
+		}
+	}
+
+	start := slices.Concat(diff.changed, diff.added)
+
+	// a channel per named app to start, closed once the app is started; the
+	// other apps it may depend on are already running
+	ready := make(map[string]chan struct{})
+	for _, app := range start {
+		if app.Name != "" {
+			ready[app.Name] = make(chan struct{})
+		}
+	}
+
+	begin := time.Now()
+	elapsed := func() string {
+		return time.Since(begin).Round(time.Millisecond).String()
+	}
+	for _, app := range start {
+		proc := ProcessDetails{pid: -1}
+		proc.setApp(appIDs[appKey(app)], app)
+		go func(proc ProcessDetails, previous []ProcessDetails) {
+			if proc.app.Name != "" {
+				defer close(ready[proc.app.Name])
+			}
+
+			var wg sync.WaitGroup
+			for _, prev := range previous {
+				if !stoppedOnReload(prev) {
+					p.logger.Info("App changed in the config, leaving the running instance", "app", proc.app.DisplayName(), "pid", prev.pid)
+					continue
+				}
+				p.logger.Info("App changed in the config, restarting it", "app", proc.app.DisplayName(), "pid", prev.pid)
+				wg.Add(1)
+				go func(prev ProcessDetails) {
+					defer wg.Done()
+					p.stopProcess(ctx, prev, 0)
+				}(prev)
+			}
+			wg.Wait()
+
+			for _, dep := range proc.app.DependsOn {
+				if depReady, found := ready[dep]; found {
+					p.logger.Debug("Waiting for dependency", "app", proc.app.DisplayName(), "dependency", dep)
+					select {
+					case <-depReady:
+					case <-launchCtx.Done():
+					}
+				}
+			}
+
+			result := appStart{proc: proc, err: launchCtx.Err()}
+			if result.err == nil {
+				runningProcs, err := ps.Processes()
+				if err != nil {
+					p.logger.Warn("Error listing processed", "error", err)
+				}
+				result.procs, result.err = p.launchApp(launchCtx, proc.appID, proc.app, nil, runningProcs, waitCheck, elapsed)
+			}
+			select {
+			case started <- result:
+			case <-done:
+			}
+		}(proc, stopping[appKey(app)])
+	}
+	return len(start)
+}
+
+// stoppedOnReload tells if a process of an app removed or changed by a config
+// reload is stopped: the processes with killOnExit unset and the reused
+// instances, started by the user, are left running
+func stoppedOnReload(proc ProcessDetails) bool {
+	return proc.killOnExit && proc.launched()
+}
+
+// currentApp returns the app of the current config having the same key,
+// the config of an app may change while its restart is pending
+func (p *ProcessHander) currentApp(app AppConfig) (int, AppConfig, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	key := appKey(app)
+	for appID, current := range p.apps {
+		if appKey(current) == key {
+			return appID, current, true
+		}
+	}
+	return 0, AppConfig{}, false
+}
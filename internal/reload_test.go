@@ -0,0 +1,121 @@
+package internal
+
+import (
+	"maps"
+	"slices"
+	"testing"
+)
+
+func TestDiffApps(t *testing.T) {
+	old := []AppConfig{
+		{Name: "db", Path: "/bin/db"},
+		{Name: "web", Path: "/bin/web", Args: []string{"--port", "80"}},
+		{Name: "cache", Path: "/bin/cache"},
+		{Path: "/bin/worker", Args: []string{"-n", "1"}},
+		{Path: "/bin/tool"},
+	}
+	new := []AppConfig{
+		{Name: "db", Path: "/bin/db", Restart: RestartAlways},
+		{Name: "web", Path: "/bin/web", Args: []string{"--port", "8080"}},
+		{Path: "/bin/worker", Args: []string{"-n", "2"}},
+		{Path: "/bin/tool", Env: map[string]string{"DEBUG": "1"}},
+		{Name: "queue", Path: "/bin/queue"},
+	}
+
+	diff := diffApps(old, new)
+	tests := []struct {
+		name string
+		got  []AppConfig
+		want []string
+	}{
+		// the unnamed apps are identified by path and args, changing their
+		// args removes the previous app and adds a new one
+		{"added", diff.added, []string{"worker", "queue"}},
+		{"removed", diff.removed, []string{"cache", "worker"}},
+		{"changed", diff.changed, []string{"web", "tool"}},
+		{"unchanged", diff.unchanged, []string{"db"}},
+	}
+	for _, tt := range tests {
+		if got := appNames(tt.got); !slices.Equal(got, tt.want) {
+			t.Errorf("%s apps = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+	if args := diff.added[0].Args; !slices.Equal(args, []string{"-n", "2"}) {
+		t.Errorf("added worker args = %q, want the new ones", args)
+	}
+}
+
+func TestAppKey(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b AppConfig
+		same bool
+	}{
+		{"same name", AppConfig{Name: "a", Path: "/bin/a"}, AppConfig{Name: "a", Path: "/bin/b"}, true},
+		{"other name", AppConfig{Name: "a", Path: "/bin/a"}, AppConfig{Name: "b", Path: "/bin/a"}, false},
+		{"same path and args", AppConfig{Path: "/bin/a", Args: []string{"x"}}, AppConfig{Path: "/bin/a", Args: []string{"x"}}, true},
+		{"other args", AppConfig{Path: "/bin/a", Args: []string{"x"}}, AppConfig{Path: "/bin/a", Args: []string{"y"}}, false},
+		{"args split differently", AppConfig{Path: "/bin/a", Args: []string{"x y"}}, AppConfig{Path: "/bin/a", Args: []string{"x", "y"}}, false},
+		{"named and unnamed", AppConfig{Name: "a", Path: "/bin/a"}, AppConfig{Path: "/bin/a"}, false},
+	}
+	for _, tt := range tests {
+		if same := appKey(tt.a) == appKey(tt.b); same != tt.same {
+			t.Errorf("%s: same key = %v, want %v", tt.name, same, tt.same)
+		}
+	}
+}
+
+func TestLaunchParams(t *testing.T) {
+	base := AppConfig{Name: "a", Path: "/bin/a"}
+	tests := []struct {
+		name    string
+		app     AppConfig
+		changed bool
+	}{
+		{"args", AppConfig{Name: "a", Path: "/bin/a", Args: []string{"-v"}}, true},
+		{"env", AppConfig{Name: "a", Path: "/bin/a", Env: map[string]string{"A": "1"}}, true},
+		{"working dir", AppConfig{Name: "a", Path: "/bin/a", WorkingDir: "/tmp"}, true},
+		{"stdout", AppConfig{Name: "a", Path: "/bin/a", Stdout: "a.log"}, true},
+		{"adopt", AppConfig{Name: "a", Path: "/bin/a", Adopt: AdoptAll}, true},
+		{"restart", AppConfig{Name: "a", Path: "/bin/a", Restart: RestartAlways}, false},
+		{"kill on exit", AppConfig{Name: "a", Path: "/bin/a", KillOnExit: true}, false},
+		{"on exit", AppConfig{Name: "a", Path: "/bin/a", OnExit: []ExitRule{{On: "any", Action: ExitActionStop}}}, false},
+	}
+	for _, tt := range tests {
+		diff := diffApps([]AppConfig{base}, []AppConfig{tt.app})
+		if changed := len(diff.changed) == 1; changed != tt.changed {
+			t.Errorf("%s: changed = %v, want %v", tt.name, changed, tt.changed)
+		}
+	}
+}
+
+func TestStoppedOnReload(t *testing.T) {
+	tests := []struct {
+		name string
+		proc ProcessDetails
+		want bool
+	}{
+		{"launched", ProcessDetails{killOnExit: true, watcher: &childWatcher{}}, true},
+		{"launched without killOnExit", ProcessDetails{watcher: &childWatcher{}}, false},
+		{"reused", ProcessDetails{killOnExit: true, watcher: &pollWatcher{}}, false},
+	}
+	for _, tt := range tests {
+		if got := stoppedOnReload(tt.proc); got != tt.want {
+			t.Errorf("%s: stoppedOnReload() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestGroupTriggerUpdate(t *testing.T) {
+	trigger := newGroupTrigger(&TriggerConfig{Policy: TriggerAll}, 3)
+	trigger.closed = map[int]bool{0: true, 2: true}
+
+	// app 0 is removed, app 2 becomes app 1 and a new app is added
+	trigger.update(map[int]int{1: 0, 2: 1}, 3)
+	if want := map[int]bool{1: true}; !maps.Equal(trigger.closed, want) {
+		t.Errorf("closed = %v, want %v", trigger.closed, want)
+	}
+	if trigger.apps != 3 {
+		t.Errorf("apps = %d, want 3", trigger.apps)
+	}
+}
@@ -0,0 +1,93 @@
+package internal
+
+import (
+	"time"
+)
+
+const (
+	RestartNever     = "never"
+	RestartOnFailure = "on-failure"
+	RestartAlways    = "always"
+
+	defaultMaxRetries      = 5
+	defaultRestartDelay    = 1 * time.Second
+	defaultRestartMaxDelay = 1 * time.Minute
+	defaultRestartWindow   = 1 * time.Minute
+)
+
+// shouldRestart checks the restart policy of an exited process
+func (p *ProcessHander) shouldRestart(proc ProcessDetails, exit processExit) bool {
+	switch proc.app.Restart {
+	case RestartAlways:
+		return true
+	case RestartOnFailure:
+		if !exit.failed() {
+			p.logger.Debug("App exited successfully, not restarting", "path", proc.path)
+			return false
+		}
+		return true
+	}
+	return false
+}
+
+// restartDelay returns the backoff to apply before restarting a process,
+// the delay doubles with each restart done within the crash-loop window and
+// the app is given up once it has been restarted maxRetries times within this window
+func (p *ProcessHander) restartDelay(proc *ProcessDetails) (time.Duration, bool) {
+	app := proc.app
+	maxRetries := app.MaxRetries
+	if maxRetries <= 0 {
+		maxRetries = defaultMaxRetries
+	}
+	window := durationOrDefault(app.RestartWindow, defaultRestartWindow)
+
+	// forget the restarts done outside of the crash-loop window, the history
+	// is copied as the instances of an app start with the same one
+	now := time.Now()
+	var recent []time.Time
+	for _, t := range proc.restarts {
+		if now.Sub(t) < window {
+			recent = append(recent, t)
+		}
+	}
+	proc.restarts = recent
+
+	if len(proc.restarts) >= maxRetries {
+		p.logger.Warn("App restarted too many times, giving up", "path", app.Path, "restarts", len(proc.restarts), "window", window.String())
+		return 0, false
+	}
+
+	delay := durationOrDefault(app.RestartDelay, defaultRestartDelay)
+	maxDelay := durationOrDefault(app.RestartMaxDelay, defaultRestartMaxDelay)
+	for range proc.restarts {
+		delay *= 2
+		if delay >= maxDelay {
+			delay = maxDelay
+			break
+		}
+	}
+	proc.restarts = append(proc.restarts, now)
+	return delay, true
+}
+
+// scheduleRestart waits for the backoff delay before asking for the app to be restarted
+func (p *ProcessHander) scheduleRestart(proc ProcessDetails, delay time.Duration, restarts chan ProcessDetails, done chan struct{}) {
+	p.logger.Info("Restarting app", "path", proc.path, "delay", delay.String(), "attempt", len(proc.restarts))
+	select {
+	case <-time.After(delay):
+	case <-done:
+		return
+	}
+
+	select {
+	case restarts <- proc:
+	case <-done:
+	}
+}
+
+func durationOrDefault(d Duration, defaultValue time.Duration) time.Duration {
+	if d <= 0 {
+		return defaultValue
+	}
+	return time.Duration(d)
+}
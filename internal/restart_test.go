@@ -0,0 +1,61 @@
+package internal
+
+import (
+	"io"
+	"log/slog"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestRestartDelay(t *testing.T) {
+	p := NewProcessHander(slog.New(slog.NewTextHandler(io.Discard, nil)), "", nil)
+	proc := ProcessDetails{app: AppConfig{
+		MaxRetries:      4,
+		RestartDelay:    Duration(time.Second),
+		RestartMaxDelay: Duration(5 * time.Second),
+	}}
+
+	var delays []time.Duration
+	for {
+		delay, restart := p.restartDelay(&proc)
+		if !restart {
+			break
+		}
+		delays = append(delays, delay)
+	}
+	want := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second}
+	if !slices.Equal(delays, want) {
+		t.Errorf("delays = %v, want %v", delays, want)
+	}
+}
+
+func TestRestartDelayWindow(t *testing.T) {
+	p := NewProcessHander(slog.New(slog.NewTextHandler(io.Discard, nil)), "", nil)
+	old := time.Now().Add(-2 * time.Minute)
+	proc := ProcessDetails{
+		app:      AppConfig{RestartWindow: Duration(time.Minute)},
+		restarts: []time.Time{old, old, old},
+	}
+	if delay, restart := p.restartDelay(&proc); !restart || delay != defaultRestartDelay {
+		t.Errorf("restartDelay() = %s, %v, want %s once the old restarts are forgotten", delay, restart, defaultRestartDelay)
+	}
+}
+
+func TestRestartDelayInstances(t *testing.T) {
+	p := NewProcessHander(slog.New(slog.NewTextHandler(io.Discard, nil)), "", nil)
+	history := make([]time.Time, 1, 8)
+	history[0] = time.Now()
+
+	// the instances of an app start with the same history but keep their own
+	first := ProcessDetails{restarts: slices.Clone(history)}
+	second := ProcessDetails{restarts: slices.Clone(history)}
+	p.restartDelay(&first)
+	p.restartDelay(&first)
+	if len(second.restarts) != 1 {
+		t.Errorf("second instance has %d restarts, want 1", len(second.restarts))
+	}
+	if delay, _ := p.restartDelay(&second); delay != 2*defaultRestartDelay {
+		t.Errorf("second instance delay = %s, want %s", delay, 2*defaultRestartDelay)
+	}
+}
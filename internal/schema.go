@@ -0,0 +1,113 @@
+package internal
+
+import (
+	_ "embed"
+	"encoding/json"
+	"reflect"
+	"strings"
+)
+
+//go:generate go run ./schemagen schema.json
+
+// schemaJSON is the JSON Schema of the config file, generated from the config structs
+//
+//go:embed schema.json
+var schemaJSON []byte
+
+// schemaEnums lists the allowed values of the string fields, by struct and json field name
+var schemaEnums = map[string][]string{
+	"ConfigFile.mode":      {ModeOnce, ModeSupervise},
+	"AppConfig.adopt":      {AdoptFirst, AdoptAll, AdoptNewest, AdoptOldest},
+	"AppConfig.closedWhen": {ClosedWhenAny, ClosedWhenAll},
+	"AppConfig.restart":    {RestartNever, RestartOnFailure, RestartAlways},
+	"ExitRule.action":      {ExitActionStop, ExitActionRestart, ExitActionIgnore},
+	"ProbeConfig.type":     {ProbeTCP, ProbeHTTP, ProbeFile, ProbeLog, ProbeExec, ProbeHeartbeat},
+	"TriggerConfig.policy": {TriggerAny, TriggerAll, TriggerPrimary, TriggerCount},
+}
+
+// schemaType is implemented by the config types which are not described by their Go type
+type schemaType interface {
+	jsonSchema() map[string]any
+}
+
+var schemaTypeInterface = reflect.TypeFor[schemaType]()
+
+// Schema returns the JSON Schema of the config file
+func Schema() []byte {
+	return schemaJSON
+}
+
+// GenerateSchema builds the JSON Schema of the config file from the config structs
+func GenerateSchema() ([]byte, error) {
+	definitions := make(map[string]any)
+	schema := map[string]any{
+		"$schema": "http://json-schema.org/draft-07/schema#",
+		"title":   "RunSyncApps configuration",
+	}
+	for key, value := range typeSchema(reflect.TypeFor[ConfigFile](), definitions, true) {
+		schema[key] = value
+	}
+	schema["definitions"] = definitions
+
+	data, err := json.MarshalIndent(schema, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// typeSchema returns the schema of a type, the structs are added to the
+// definitions and referenced unless inline is set
+func typeSchema(t reflect.Type, definitions map[string]any, inline bool) map[string]any {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Implements(schemaTypeInterface) {
+		return reflect.Zero(t).Interface().(schemaType).jsonSchema()
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		if !inline {
+			if _, found := definitions[t.Name()]; !found {
+				definitions[t.Name()] = nil // placeholder for recursive types
+				definitions[t.Name()] = typeSchema(t, definitions, true)
+			}
+			return map[string]any{"$ref": "#/definitions/" + t.Name()}
+		}
+		properties := make(map[string]any)
+		for i := 0; i < t.NumField(); i++ {
+			name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+			if name == "" || name == "-" {
+				continue
+			}
+			property := typeSchema(t.Field(i).Type, definitions, false)
+			if enum, found := schemaEnums[t.Name()+"."+name]; found {
+				property["enum"] = enum
+			}
+			properties[name] = property
+		}
+		return map[string]any{
+			"type":                 "object",
+			"properties":           properties,
+			"additionalProperties": false,
+		}
+	case reflect.Map:
+		return map[string]any{
+			"type":                 "object",
+			"additionalProperties": typeSchema(t.Elem(), definitions, false),
+		}
+	case reflect.Slice:
+		return map[string]any{
+			"type":  "array",
+			"items": typeSchema(t.Elem(), definitions, false),
+		}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int64:
+		return map[string]any{"type": "integer", "minimum": 0}
+	}
+	return map[string]any{}
+}
@@ -0,0 +1,16 @@
+package internal
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestSchemaUpToDate(t *testing.T) {
+	generated, err := GenerateSchema()
+	if err != nil {
+		t.Fatalf("GenerateSchema() error: %v", err)
+	}
+	if !bytes.Equal(generated, Schema()) {
+		t.Error("schema.json is out of date, run go generate ./internal")
+	}
+}
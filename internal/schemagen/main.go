@@ -0,0 +1,26 @@
+// schemagen writes the JSON Schema of the config file, it is run by go generate
+package main
+
+import (
+	"fmt"
+	"os"
+
+	i "github.com/clemthi/runsyncapps/internal"
+)
+
+func main() {
+	if len(os.Args) != 2 {
+		fmt.Fprintln(os.Stderr, "usage: schemagen <output file>")
+		os.Exit(1)
+	}
+
+	schema, err := i.GenerateSchema()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if err := os.WriteFile(os.Args[1], schema, 0o644); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
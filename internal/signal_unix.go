@@ -0,0 +1,91 @@
+//go:build !windows
+
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+)
+
+var stopSignals = map[string]syscall.Signal{
+	"HUP":  syscall.SIGHUP,
+	"INT":  syscall.SIGINT,
+	"QUIT": syscall.SIGQUIT,
+	"TERM": syscall.SIGTERM,
+	"USR1": syscall.SIGUSR1,
+	"USR2": syscall.SIGUSR2,
+	"KILL": syscall.SIGKILL,
+}
+
+// parseSignal converts a signal name (SIGTERM, TERM, term...) to a signal
+func parseSignal(name string) (syscall.Signal, error) {
+	sig, ok := stopSignals[strings.TrimPrefix(strings.ToUpper(name), "SIG")]
+	if !ok {
+		return 0, fmt.Errorf("unknown signal %q", name)
+	}
+	return sig, nil
+}
+
+// setProcessGroup starts the command in its own process group
+// so that the whole tree can be signaled at once
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+// processGroupAlive checks if a process group still has members
+func processGroupAlive(pgid int) bool {
+	return syscall.Kill(-pgid, 0) == nil
+}
+
+// sendStopSignal asks a process and its descendants to stop
+// by sending them the given signal
+func sendStopSignal(pid int, group bool, signal string) error {
+	sig, err := parseSignal(signal)
+	if err != nil {
+		return err
+	}
+	return signalTree(pid, group, sig)
+}
+
+// killProcessTree kills a process and its descendants
+func killProcessTree(pid int, group bool) error {
+	return signalTree(pid, group, syscall.SIGKILL)
+}
+
+// signalTree signals the process group when the process leads one,
+// then the descendants which are not part of it (found with their parent pid)
+func signalTree(pid int, group bool, sig syscall.Signal) error {
+	// list descendants first as they are reparented once their parent exits
+	descendants := processTree(pid)
+
+	target := pid
+	if group {
+		target = -pid
+	}
+	err := syscall.Kill(target, sig)
+	if errors.Is(err, syscall.ESRCH) {
+		err = nil
+	}
+
+	for _, child := range descendants {
+		if pgid, errPgid := syscall.Getpgid(child); group && errPgid == nil && pgid == pid {
+			continue
+		}
+		if errChild := syscall.Kill(child, sig); errChild != nil && !errors.Is(errChild, syscall.ESRCH) && err == nil {
+			err = errChild
+		}
+	}
+	return err
+}
+
+// exitSignal returns the name of the signal which terminated a process
+func exitSignal(state *os.ProcessState) string {
+	if status, ok := state.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+		return status.Signal().String()
+	}
+	return ""
+}
@@ -0,0 +1,49 @@
+package internal
+
+import (
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// setProcessGroup does nothing on Windows, trees are handled with their parent pid
+func setProcessGroup(cmd *exec.Cmd) {}
+
+// processGroupAlive always returns false as there are no process groups on Windows
+func processGroupAlive(pgid int) bool {
+	return false
+}
+
+// sendStopSignal asks a process and its descendants to stop, Windows has no
+// signals so anything but KILL is sent as a close request to the process
+// windows (taskkill without /F) which lets the application save its state
+func sendStopSignal(pid int, group bool, signal string) error {
+	if strings.TrimPrefix(strings.ToUpper(signal), "SIG") == "KILL" {
+		return killProcessTree(pid, group)
+	}
+	return exec.Command("taskkill", "/T", "/PID", strconv.Itoa(pid)).Run()
+}
+
+// killProcessTree kills a process and its descendants
+func killProcessTree(pid int, group bool) error {
+	// list descendants first as they cannot be found once their parent exits
+	descendants := processTree(pid)
+
+	var err error
+	for _, target := range append([]int{pid}, descendants...) {
+		process, errFind := os.FindProcess(target)
+		if errFind != nil {
+			continue
+		}
+		if errKill := process.Kill(); errKill != nil && target == pid {
+			err = errKill
+		}
+	}
+	return err
+}
+
+// exitSignal returns an empty string as there are no signals on Windows
+func exitSignal(state *os.ProcessState) string {
+	return ""
+}
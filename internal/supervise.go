@@ -0,0 +1,180 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	ps "github.com/keybase/go-ps"
+)
+
+const (
+	ModeOnce      = "once"
+	ModeSupervise = "supervise"
+
+	StateIdle     = "idle"
+	StateStarting = "starting"
+	StateRunning  = "running"
+	StateStopping = "stopping"
+
+	relaunchPollInterval = 1 * time.Second
+)
+
+// State returns the current state of the apps cycle
+func (p *ProcessHander) State() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.state
+}
+
+func (p *ProcessHander) setState(state string) {
+	p.mu.Lock()
+	previous := p.state
+	p.state = state
+	p.mu.Unlock()
+
+	if previous != state {
+		p.logger.Debug("State changed", "from", previous, "to", state)
+	}
+}
+
+// addProcess tracks the process, it returns false if it is already tracked
+func (p *ProcessHander) addProcess(proc ProcessDetails) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, found := p.procs[proc.pid]; found {
+		return false
+	}
+	p.procs[proc.pid] = proc
+	return true
+}
+
+// removeProcess stops tracking the process, it returns false if it is not tracked
+func (p *ProcessHander) removeProcess(pid int) (ProcessDetails, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	proc, found := p.procs[pid]
+	delete(p.procs, pid)
+	return proc, found
+}
+
+// processes returns a snapshot of the processes of the current cycle
+func (p *ProcessHander) processes() map[int]ProcessDetails {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	procs := make(map[int]ProcessDetails, len(p.procs))
+	for pid, proc := range p.procs {
+		procs[pid] = proc
+	}
+	return procs
+}
+
+// instanceCount returns the number of running instances of an app
+func (p *ProcessHander) instanceCount(appID int) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	count := 0
+	for _, proc := range p.procs {
+		if proc.appID == appID {
+			count++
+		}
+	}
+	return count
+}
+
+func (p *ProcessHander) processCount() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.procs)
+}
+
+// WaitRelaunch waits for the apps to be relaunched in supervise mode: once
+// the instances of the apps flagged with useExistingInstance are all closed,
+// the apps are relaunched when one of them is started again by the user.
+// Without such apps, the apps are relaunched right away.
+func (p *ProcessHander) WaitRelaunch(ctx context.Context, apps []AppConfig) error {
+	var triggers []AppConfig
+	for _, app := range apps {
+		if app.UseExistingInstance {
+			triggers = append(triggers, app)
+		}
+	}
+
+	if len(triggers) == 0 {
+		p.logger.Info("Relaunching apps")
+		select {
+		case <-time.After(relaunchPollInterval):
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	p.logger.Info("Waiting for apps to be closed before relaunching")
+	for p.findTrigger(triggers) != nil {
+		if err := sleepContext(ctx, relaunchPollInterval); err != nil {
+			return err
+		}
+	}
+
+	p.logger.Info("Waiting for an app to be started to relaunch the apps")
+	for {
+		if app := p.findTrigger(triggers); app != nil {
+			p.logger.Info("App started, relaunching apps", "app", app.DisplayName())
+			return nil
+		}
+		if err := sleepContext(ctx, relaunchPollInterval); err != nil {
+			return err
+		}
+	}
+}
+
+// findTrigger returns the first app having a running instance
+func (p *ProcessHander) findTrigger(apps []AppConfig) *AppConfig {
+	runningProcs, err := ps.Processes()
+	if err != nil {
+		p.logger.Warn("Error listing processed", "error", err)
+		return nil
+	}
+	for i, app := range apps {
+		envVars, err := appEnvVars(app)
+		if err != nil {
+			continue
+		}
+		if pids, _ := p.findExistingPids(app, envVars, runningProcs); len(pids) > 0 {
+			return &apps[i]
+		}
+	}
+	return nil
+}
+
+// FailedEarly tells if a cycle of the apps ended with a startup failure, or
+// with an app failure within the crash-loop window after the launch, the
+// relaunches of such cycles are delayed
+func FailedEarly(err error, uptime time.Duration) bool {
+	var startupErr *StartupError
+	var exitErr *AppExitError
+	return errors.As(err, &startupErr) || errors.As(err, &exitErr) && uptime < defaultRestartWindow
+}
+
+// RelaunchDelay returns the delay before relaunching the apps after several
+// consecutive early failures, doubled on each failure up to a minute
+func RelaunchDelay(failures int) time.Duration {
+	delay := defaultRestartDelay
+	for range failures - 1 {
+		delay *= 2
+		if delay >= defaultRestartMaxDelay {
+			return defaultRestartMaxDelay
+		}
+	}
+	return delay
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	select {
+	case <-time.After(d):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
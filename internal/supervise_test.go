@@ -0,0 +1,51 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestRelaunchDelay(t *testing.T) {
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{1, time.Second},
+		{2, 2 * time.Second},
+		{3, 4 * time.Second},
+		{7, time.Minute},
+		{100, time.Minute},
+	}
+	for _, tt := range tests {
+		if got := RelaunchDelay(tt.failures); got != tt.want {
+			t.Errorf("RelaunchDelay(%d) = %s, want %s", tt.failures, got, tt.want)
+		}
+	}
+}
+
+func TestFailedEarly(t *testing.T) {
+	startupErr := fmt.Errorf("error launching apps : %w", &StartupError{Err: errors.New("not ready")})
+	exitErr := &AppExitError{App: "a", ExitCode: 1}
+
+	tests := []struct {
+		name   string
+		err    error
+		uptime time.Duration
+		want   bool
+	}{
+		{"startup failure", startupErr, time.Second, true},
+		{"slow startup failure", startupErr, 2 * time.Minute, true},
+		{"crash after the launch", exitErr, 5 * time.Second, true},
+		{"crash after a while", exitErr, 2 * time.Minute, false},
+		{"apps closed", nil, time.Second, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FailedEarly(tt.err, tt.uptime); got != tt.want {
+				t.Errorf("FailedEarly() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
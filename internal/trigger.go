@@ -0,0 +1,88 @@
+package internal
+
+import (
+	"slices"
+	"strings"
+)
+
+const (
+	TriggerAny     = "any"
+	TriggerAll     = "all"
+	TriggerPrimary = "primary"
+	TriggerCount   = "count"
+)
+
+// TriggerConfig tells which closed apps stop the group, an app is closed when
+// its exit would stop the group (critical app or stop exit rule)
+type TriggerConfig struct {
+	Policy  string   `json:"policy"`
+	Primary []string `json:"primary"`
+	Count   int      `json:"count"`
+}
+
+// checkTrigger checks the trigger policy against the apps of the group
+func (v *validator) checkTrigger(path string, trigger *TriggerConfig, apps []AppConfig) {
+	if trigger == nil {
+		return
+	}
+
+	// unknown policies are reported by the schema
+	switch strings.ToLower(trigger.Policy) {
+	case TriggerPrimary:
+		if len(trigger.Primary) == 0 {
+			v.addf(childPath(path, "primary"), "trigger policy %q requires primary apps", trigger.Policy)
+		}
+		for i, name := range trigger.Primary {
+			if !slices.ContainsFunc(apps, func(app AppConfig) bool { return app.Name == name }) {
+				v.addf(indexPath(childPath(path, "primary"), i), "unknown primary app %q", name)
+			}
+		}
+	case TriggerCount:
+		if trigger.Count < 1 || trigger.Count > len(apps) {
+			v.addf(childPath(path, "count"), "trigger count must be between 1 and %d", len(apps))
+		}
+	}
+}
+
+// groupTrigger keeps track of the closed apps of a cycle
+type groupTrigger struct {
+	config TriggerConfig
+	apps   int
+	closed map[int]bool
+}
+
+func newGroupTrigger(config *TriggerConfig, apps int) *groupTrigger {
+	t := &groupTrigger{apps: apps, closed: make(map[int]bool)}
+	if config != nil {
+		t.config = *config
+	}
+	return t
+}
+
+// close records the closed app and tells if the group must be stopped
+func (t *groupTrigger) close(proc ProcessDetails) bool {
+	t.closed[proc.appID] = true
+
+	switch strings.ToLower(t.config.Policy) {
+	case TriggerAll:
+		return len(t.closed) >= t.apps
+	case TriggerPrimary:
+		return slices.Contains(t.config.Primary, proc.app.Name)
+	case TriggerCount:
+		return len(t.closed) >= t.config.Count
+	}
+	return true
+}
+
+// update maps the closed apps to their index in the reloaded config,
+// the apps removed from the config are forgotten
+func (t *groupTrigger) update(appIDs map[int]int, apps int) {
+	closed := make(map[int]bool, len(t.closed))
+	for appID := range t.closed {
+		if newID, found := appIDs[appID]; found {
+			closed[newID] = true
+		}
+	}
+	t.closed = closed
+	t.apps = apps
+}
@@ -0,0 +1,92 @@
+package internal
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os/exec"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestGroupTriggerClose(t *testing.T) {
+	type closing struct {
+		appID int
+		name  string
+	}
+	tests := []struct {
+		name    string
+		config  *TriggerConfig
+		apps    int
+		closing []closing
+		want    []bool
+	}{
+		{"default", nil, 2, []closing{{0, "a"}}, []bool{true}},
+		{"any", &TriggerConfig{Policy: TriggerAny}, 2, []closing{{1, "b"}}, []bool{true}},
+		{
+			// the instances of an app count once
+			"all", &TriggerConfig{Policy: TriggerAll}, 3,
+			[]closing{{0, "a"}, {0, "a"}, {1, "b"}, {2, "c"}},
+			[]bool{false, false, false, true},
+		},
+		{"all case insensitive", &TriggerConfig{Policy: "ALL"}, 2, []closing{{0, "a"}, {1, "b"}}, []bool{false, true}},
+		{
+			"primary", &TriggerConfig{Policy: TriggerPrimary, Primary: []string{"web", "api"}}, 3,
+			[]closing{{0, "db"}, {2, "api"}},
+			[]bool{false, true},
+		},
+		{
+			"count", &TriggerConfig{Policy: TriggerCount, Count: 2}, 3,
+			[]closing{{0, "a"}, {0, "a"}, {2, "c"}},
+			[]bool{false, false, true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			trigger := newGroupTrigger(tt.config, tt.apps)
+			var got []bool
+			for _, c := range tt.closing {
+				got = append(got, trigger.close(ProcessDetails{appID: c.appID, app: AppConfig{Name: c.name}}))
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("close() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTriggerAllIgnoredApps(t *testing.T) {
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("sh not found")
+	}
+	notCritical := false
+
+	// the failure of a is not enough to stop the group, b is not critical and
+	// c is ignored by its exit rule: they never count as closed and the group
+	// ends once no process is left
+	apps := []AppConfig{
+		{Name: "a", Path: sh, Args: []string{"-c", "exit 1"}},
+		{Name: "b", Path: sh, Args: []string{"-c", "sleep 0.3; exit 1"}, Critical: &notCritical},
+		{Name: "c", Path: sh, Args: []string{"-c", "sleep 0.5; exit 2"}, OnExit: []ExitRule{{On: "2", Action: ExitActionIgnore}}},
+	}
+	p := NewProcessHander(slog.New(slog.NewTextHandler(io.Discard, nil)), t.TempDir(), nil)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := p.StartProcesses(ctx, apps, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	begin := time.Now()
+	if err := p.CheckRunningProcesses(ctx, &TriggerConfig{Policy: TriggerAll}); err != nil {
+		t.Fatalf("CheckRunningProcesses() = %v, want nil once no app is left", err)
+	}
+	if elapsed := time.Since(begin); elapsed < 400*time.Millisecond {
+		t.Errorf("group stopped after %s, before the last app exited", elapsed)
+	}
+	if n := p.processCount(); n != 0 {
+		t.Errorf("%d processes left", n)
+	}
+}
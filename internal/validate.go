@@ -0,0 +1,329 @@
+package internal
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// stopSignalNames are the signals accepted by stopSignal on all platforms
+var stopSignalNames = []string{"HUP", "INT", "QUIT", "TERM", "USR1", "USR2", "KILL"}
+
+// ConfigProblem is an invalid value of a config file
+type ConfigProblem struct {
+	File    string
+	Line    int    // 0 when unknown
+	Path    string // e.g. applications[0].path
+	Message string
+}
+
+// ConfigError lists all the problems found in the config files
+type ConfigError struct {
+	Problems []ConfigProblem
+}
+
+func (e *ConfigError) Error() string {
+	var b strings.Builder
+	b.WriteString("invalid config:")
+	for _, problem := range e.Problems {
+		b.WriteString("\n  ")
+		switch {
+		case problem.Line > 0:
+			fmt.Fprintf(&b, "%s:%d: ", problem.File, problem.Line)
+		case problem.File != "":
+			fmt.Fprintf(&b, "%s: ", problem.File)
+		}
+		if problem.Path != "" {
+			fmt.Fprintf(&b, "%s: ", problem.Path)
+		}
+		b.WriteString(problem.Message)
+	}
+	return b.String()
+}
+
+// validator collects the problems of the config files
+type validator struct {
+	positions positions
+	problems  []ConfigProblem
+}
+
+func (v *validator) addf(path string, format string, args ...any) {
+	p, filePath := v.positions.lookup(path)
+	v.problems = append(v.problems, ConfigProblem{
+		File:    p.file,
+		Line:    p.line,
+		Path:    filePath,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// hasProblem tells if a problem was already found for a value
+func (v *validator) hasProblem(path string) bool {
+	p, filePath := v.positions.lookup(path)
+	return slices.ContainsFunc(v.problems, func(problem ConfigProblem) bool {
+		return problem.File == p.file && problem.Path == filePath
+	})
+}
+
+// err returns the problems found as a ConfigError, nil if there is none
+func (v *validator) err() error {
+	if len(v.problems) == 0 {
+		return nil
+	}
+	slices.SortStableFunc(v.problems, func(a, b ConfigProblem) int {
+		return cmp.Or(cmp.Compare(a.File, b.File), cmp.Compare(a.Line, b.Line), cmp.Compare(a.Path, b.Path))
+	})
+	return &ConfigError{Problems: v.problems}
+}
+
+// checkDocument checks a decoded JSON document against the config schema
+func (v *validator) checkDocument(doc any) error {
+	var schema map[string]any
+	if err := json.Unmarshal(Schema(), &schema); err != nil {
+		return fmt.Errorf("invalid config schema: %w", err)
+	}
+	definitions, _ := schema["definitions"].(map[string]any)
+	v.checkSchema("", doc, schema, definitions)
+	return nil
+}
+
+// checkSchema checks a value against the subset of JSON Schema used by the
+// config schema: $ref, type, properties, additionalProperties, items, enum,
+// minimum and pattern. Field names are case sensitive.
+func (v *validator) checkSchema(path string, value any, schema map[string]any, definitions map[string]any) {
+	if ref, ok := schema["$ref"].(string); ok {
+		schema, _ = definitions[strings.TrimPrefix(ref, "#/definitions/")].(map[string]any)
+	}
+	if value == nil || schema == nil {
+		return
+	}
+
+	if types := schemaTypes(schema["type"]); len(types) > 0 && !hasType(types, jsonType(value)) {
+		v.addf(path, "expected %s, got %s", strings.Join(types, " or "), jsonType(value))
+		return
+	}
+
+	switch value := value.(type) {
+	case map[string]any:
+		properties, _ := schema["properties"].(map[string]any)
+		for key, child := range value {
+			if property, found := properties[key].(map[string]any); found {
+				v.checkSchema(childPath(path, key), child, property, definitions)
+				continue
+			}
+			switch additional := schema["additionalProperties"].(type) {
+			case map[string]any:
+				v.checkSchema(childPath(path, key), child, additional, definitions)
+			case bool:
+				if !additional {
+					v.unknownField(childPath(path, key), key, properties)
+				}
+			}
+		}
+	case []any:
+		if items, ok := schema["items"].(map[string]any); ok {
+			for i, child := range value {
+				v.checkSchema(indexPath(path, i), child, items, definitions)
+			}
+		}
+	case string:
+		if pattern, ok := schema["pattern"].(string); ok && !regexp.MustCompile(pattern).MatchString(value) {
+			expected, found := schema["description"].(string)
+			if !found {
+				expected = "a value matching " + pattern
+			}
+			v.addf(path, "invalid value %q, expected %s", value, expected)
+		}
+	case float64:
+		if minimum, ok := schema["minimum"].(float64); ok && value < minimum {
+			v.addf(path, "must be at least %v, got %v", minimum, value)
+		}
+	}
+
+	if enum, ok := schema["enum"].([]any); ok && !slices.Contains(enum, value) {
+		values := make([]string, len(enum))
+		for i, allowed := range enum {
+			values[i] = fmt.Sprint(allowed)
+		}
+		v.addf(path, "invalid value %q, expected one of %s", fmt.Sprint(value), strings.Join(values, ", "))
+	}
+}
+
+func (v *validator) unknownField(path string, key string, properties map[string]any) {
+	for name := range properties {
+		if strings.EqualFold(name, key) {
+			v.addf(path, "unknown field %q, did you mean %q?", key, name)
+			return
+		}
+	}
+	v.addf(path, "unknown field %q", key)
+}
+
+// schemaTypes returns the types allowed by a schema
+func schemaTypes(t any) []string {
+	switch t := t.(type) {
+	case string:
+		return []string{t}
+	case []any:
+		types := make([]string, 0, len(t))
+		for _, name := range t {
+			types = append(types, fmt.Sprint(name))
+		}
+		return types
+	}
+	return nil
+}
+
+// hasType tells if the type is allowed, integers being numbers too
+func hasType(types []string, t string) bool {
+	return slices.Contains(types, t) || t == "integer" && slices.Contains(types, "number")
+}
+
+// jsonType returns the JSON Schema type of a decoded JSON value
+func jsonType(value any) string {
+	switch value := value.(type) {
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case float64:
+		if value == math.Trunc(value) {
+			return "integer"
+		}
+		return "number"
+	}
+	return "null"
+}
+
+// checkConfig checks the values of the config
+func (v *validator) checkConfig(c *ConfigFile) {
+	if len(c.Groups) > 0 && len(c.Applications) > 0 {
+		v.addf("groups", "applications must be defined either at the top level or in groups")
+	}
+
+	names := make(map[string]bool)
+	for index, group := range c.AppGroups() {
+		path := ""
+		if len(c.Groups) > 0 {
+			path = indexPath("groups", index)
+		}
+		if group.Name != "" {
+			if names[group.Name] {
+				v.addf(childPath(path, "name"), "duplicated group name %q", group.Name)
+			}
+			names[group.Name] = true
+		}
+		v.checkGroup(path, group)
+	}
+}
+
+func (v *validator) checkGroup(path string, group GroupConfig) {
+	appsPath := childPath(path, "applications")
+	if len(group.Applications) == 0 {
+		v.addf(appsPath, "no application defined")
+	}
+	for index, app := range group.Applications {
+		v.checkApp(indexPath(appsPath, index), app)
+	}
+	v.checkDependencies(appsPath, group.Applications)
+	v.checkTrigger(childPath(path, "trigger"), group.Trigger, group.Applications)
+}
+
+func (v *validator) checkApp(path string, app AppConfig) {
+	// the executable is not checked when the path has undefined variables
+	if app.Path == "" {
+		v.addf(childPath(path, "path"), "missing path")
+	} else if !v.hasProblem(childPath(path, "path")) {
+		if err := checkExecutable(app.Path, app.WorkingDir); err != nil {
+			v.addf(childPath(path, "path"), "%s", err)
+		}
+	}
+	if app.WorkingDir != "" {
+		if info, err := os.Stat(app.WorkingDir); err != nil || !info.IsDir() {
+			v.addf(childPath(path, "workingDir"), "directory %s not found", app.WorkingDir)
+		}
+	}
+	if app.EnvFile != "" {
+		if _, err := os.Stat(app.EnvFile); err != nil {
+			v.addf(childPath(path, "envFile"), "file %s not found", app.EnvFile)
+		}
+	}
+
+	if app.Match != nil && app.Match.CmdlineRegex != "" {
+		if _, err := regexp.Compile(app.Match.CmdlineRegex); err != nil {
+			v.addf(childPath(path, "match.cmdlineRegex"), "invalid regular expression: %s", err)
+		}
+	}
+
+	if app.StopSignal != "" && !slices.Contains(stopSignalNames, strings.TrimPrefix(strings.ToUpper(app.StopSignal), "SIG")) {
+		v.addf(childPath(path, "stopSignal"), "unknown signal %q, expected one of %s", app.StopSignal, strings.Join(stopSignalNames, ", "))
+	}
+
+	for index, rule := range app.OnExit {
+		rulePath := indexPath(childPath(path, "onExit"), index)
+		// the values having a schema problem are not checked again
+		if onPath := childPath(rulePath, "on"); !v.hasProblem(onPath) && !validExitCondition(string(rule.On)) {
+			v.addf(onPath, "invalid exit condition %q", rule.On)
+		}
+		if actionPath := childPath(rulePath, "action"); !v.hasProblem(actionPath) && rule.Action == "" {
+			v.addf(actionPath, "missing action")
+		}
+	}
+
+	v.checkProbe(childPath(path, "readiness"), "readiness", app.Readiness, readinessProbeTypes)
+	v.checkProbe(childPath(path, "liveness"), "liveness", app.Liveness, livenessProbeTypes)
+}
+
+func (v *validator) checkProbe(path string, kind string, probe *ProbeConfig, types []string) {
+	// unknown probe types are reported by the schema
+	if probe == nil || !slices.Contains(schemaEnums["ProbeConfig.type"], probe.Type) {
+		return
+	}
+	if !slices.Contains(types, probe.Type) {
+		v.addf(childPath(path, "type"), "%s probe cannot be used for %s, expected one of %s", probe.Type, kind, strings.Join(types, ", "))
+		return
+	}
+	if _, err := newProbe(*probe); err != nil {
+		v.addf(path, "%s", err)
+	}
+}
+
+// validExitCondition checks the "on" field of an exit rule
+func validExitCondition(on string) bool {
+	on = strings.ToLower(strings.TrimSpace(on))
+	switch on {
+	case "any", "nonzero", "signal", "unhealthy":
+		return true
+	case "":
+		return false
+	}
+	if _, err := strconv.Atoi(on); err == nil {
+		return true
+	}
+	return regexp.MustCompile(`^(sig)?[a-z0-9]+$`).MatchString(on)
+}
+
+// checkExecutable checks that the app executable exists and can be run,
+// a relative path is resolved from the working directory
+func checkExecutable(path string, workingDir string) error {
+	if workingDir != "" && !filepath.IsAbs(path) && strings.ContainsAny(path, `/\`) {
+		path = filepath.Join(workingDir, path)
+	}
+	if _, err := exec.LookPath(path); err != nil && !errors.Is(err, exec.ErrDot) {
+		return fmt.Errorf("executable %s not found or not executable", path)
+	}
+	return nil
+}
@@ -0,0 +1,201 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// writeConfig writes a config file in a temporary directory, $EXE in the
+// content is replaced by the path of an executable
+func writeConfig(t *testing.T, dir string, name string, content string) string {
+	t.Helper()
+	executable, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(dir, name)
+	content = strings.ReplaceAll(content, "$EXE", strconv.Quote(executable))
+	if err := os.WriteFile(file, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+func TestLoadConfigProblems(t *testing.T) {
+	tests := []struct {
+		name     string
+		file     string
+		content  string
+		problems []string // "file:line: path: message"
+	}{
+		{
+			name: "valid json",
+			file: "config.json",
+			content: `{
+    "waitCheck": "1500ms",
+    "applications": [{"name": "a", "path": $EXE, "restart": "on-failure"}]
+}`,
+		},
+		{
+			name: "zero duration",
+			file: "config.yaml",
+			content: `waitCheck: "0"
+applications:
+  - path: $EXE
+    stopTimeout: 0
+`,
+		},
+		{
+			name: "json",
+			file: "config.json",
+			content: `{
+    "mode": "forever",
+    "waitcheck": 2,
+    "applications": [
+        {"name": "a", "path": $EXE, "stopTimeout": -1},
+        {"name": "b", "args": "--flag", "dependsOn": ["c"]}
+    ]
+}`,
+			problems: []string{
+				`config.json:2: mode: invalid value "forever", expected one of once, supervise`,
+				`config.json:3: waitcheck: unknown field "waitcheck", did you mean "waitCheck"?`,
+				`config.json:5: applications[0].stopTimeout: must be at least 0, got -1`,
+				`config.json:6: applications[1].args: expected array, got string`,
+				`config.json:6: applications[1].dependsOn[0]: app "b" depends on unknown app "c"`,
+				`config.json:6: applications[1].path: missing path`,
+			},
+		},
+		{
+			name: "yaml",
+			file: "config.yaml",
+			content: `applications:
+  - name: a
+    path: $EXE
+    stopSignal: SIGFOO
+  - name: a
+    path: /nonexistent/app
+`,
+			problems: []string{
+				`config.yaml:4: applications[0].stopSignal: unknown signal "SIGFOO", expected one of HUP, INT, QUIT, TERM, USR1, USR2, KILL`,
+				`config.yaml:5: applications[1].name: duplicated app name "a"`,
+				`config.yaml:6: applications[1].path: executable /nonexistent/app not found or not executable`,
+			},
+		},
+		{
+			name: "probe types",
+			file: "config.yaml",
+			content: `applications:
+  - path: $EXE
+    readiness:
+      type: heartbeat
+      path: /tmp/heartbeat
+    liveness:
+      type: log
+      path: /tmp/app.log
+`,
+			problems: []string{
+				`config.yaml:4: applications[0].readiness.type: heartbeat probe cannot be used for readiness, expected one of tcp, http, file, log, exec`,
+				`config.yaml:7: applications[0].liveness.type: log probe cannot be used for liveness, expected one of tcp, http, exec, heartbeat`,
+			},
+		},
+		{
+			name: "exit rules",
+			file: "config.yaml",
+			content: `applications:
+  - path: $EXE
+    onExit:
+      - on: 0
+        action: ignore
+      - on: 1.5
+        action: stop
+      - on: [1]
+        action: 2
+      - on: "sig-foo"
+        action: stop
+`,
+			problems: []string{
+				`config.yaml:6: applications[0].onExit[1].on: expected integer or string, got number`,
+				`config.yaml:8: applications[0].onExit[2].on: expected integer or string, got array`,
+				`config.yaml:9: applications[0].onExit[2].action: expected string, got integer`,
+				`config.yaml:10: applications[0].onExit[3].on: invalid exit condition "sig-foo"`,
+			},
+		},
+		{
+			name: "toml",
+			file: "config.toml",
+			content: `waitExit = "2 minutes"
+
+[[applications]]
+path = $EXE
+restart = "sometimes"
+`,
+			problems: []string{
+				`config.toml:1: waitExit: invalid value "2 minutes", expected number of seconds or duration such as "1500ms" or "2m"`,
+				`config.toml:5: applications[0].restart: invalid value "sometimes", expected one of never, on-failure, always`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			file := writeConfig(t, dir, tt.file, tt.content)
+
+			_, err := LoadConfigFiles([]string{file}, "", "")
+			if tt.problems == nil {
+				if err != nil {
+					t.Fatalf("LoadConfigFiles() error: %v", err)
+				}
+				return
+			}
+
+			var configErr *ConfigError
+			if !errors.As(err, &configErr) {
+				t.Fatalf("LoadConfigFiles() error = %v, want a ConfigError", err)
+			}
+			var problems []string
+			for _, problem := range configErr.Problems {
+				problems = append(problems, fmt.Sprintf("%s:%d: %s: %s", filepath.Base(problem.File), problem.Line, problem.Path, problem.Message))
+			}
+			if !slices.Equal(problems, tt.problems) {
+				t.Errorf("problems = %q, want %q", problems, tt.problems)
+			}
+		})
+	}
+}
+
+func TestLoadConfigProblemsInIncludedFile(t *testing.T) {
+	dir := t.TempDir()
+	writeConfig(t, dir, "base.yaml", `applications:
+  - name: a
+    path: $EXE
+    adopt: everything
+`)
+	file := writeConfig(t, dir, "config.json", `{
+    "include": ["base.yaml"],
+    "applications": [{"name": "a", "closedWhen": "never"}]
+}`)
+
+	_, err := LoadConfigFiles([]string{file}, "", "")
+	var configErr *ConfigError
+	if !errors.As(err, &configErr) {
+		t.Fatalf("LoadConfigFiles() error = %v, want a ConfigError", err)
+	}
+	var problems []string
+	for _, problem := range configErr.Problems {
+		problems = append(problems, fmt.Sprintf("%s:%d: %s", filepath.Base(problem.File), problem.Line, problem.Path))
+	}
+	want := []string{
+		"base.yaml:4: applications[0].adopt",
+		"config.json:3: applications[0].closedWhen",
+	}
+	if !slices.Equal(problems, want) {
+		t.Errorf("problems = %q, want %q", problems, want)
+	}
+}
@@ -0,0 +1,82 @@
+package internal
+
+import (
+	"io"
+	"os/exec"
+	"time"
+
+	ps "github.com/keybase/go-ps"
+)
+
+const watchPollInterval = 500 * time.Millisecond
+
+// processExit describes how a monitored process ended
+type processExit struct {
+	pid       int
+	exitCode  int    // -1 when unknown or killed by a signal
+	signal    string // signal which terminated the process, if any
+	err       error  // error while waiting the process
+	unhealthy bool   // the process was killed after failing its liveness probe
+}
+
+// processWatcher waits for a process to exit
+type processWatcher interface {
+	Wait() processExit
+}
+
+// childWatcher waits for a process started by runsyncapps,
+// its outputs are closed once it has exited
+type childWatcher struct {
+	cmd     *exec.Cmd
+	closers []io.Closer
+}
+
+func (w *childWatcher) Wait() processExit {
+	exit := processExit{pid: w.cmd.Process.Pid, exitCode: -1}
+	err := w.cmd.Wait()
+	closeAll(w.closers)
+	if state := w.cmd.ProcessState; state != nil {
+		exit.exitCode = state.ExitCode()
+		exit.signal = exitSignal(state)
+		return exit
+	}
+	exit.err = err
+	return exit
+}
+
+// pollWatcher waits for a process which is not a child of runsyncapps by
+// checking periodically that it is still listed, the start time is compared
+// to detect a pid reused by another process
+type pollWatcher struct {
+	pid       int
+	startTime uint64
+	hasStart  bool
+}
+
+func newPollWatcher(pid int) *pollWatcher {
+	w := &pollWatcher{pid: pid}
+	if startTime, err := processStartTime(pid); err == nil {
+		w.startTime = startTime
+		w.hasStart = true
+	}
+	return w
+}
+
+func (w *pollWatcher) Wait() processExit {
+	for w.isRunning() {
+		time.Sleep(watchPollInterval)
+	}
+	return processExit{pid: w.pid, exitCode: -1}
+}
+
+func (w *pollWatcher) isRunning() bool {
+	process, err := ps.FindProcess(w.pid)
+	if process == nil || err != nil {
+		return false
+	}
+	if w.hasStart {
+		startTime, err := processStartTime(w.pid)
+		return err == nil && startTime == w.startTime
+	}
+	return true
+}
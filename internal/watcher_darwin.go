@@ -0,0 +1,20 @@
+package internal
+
+import (
+	"golang.org/x/sys/unix"
+)
+
+// newAdoptedWatcher returns a watcher for a process not started by runsyncapps
+func newAdoptedWatcher(pid int) (processWatcher, error) {
+	return newPollWatcher(pid), nil
+}
+
+// processStartTime returns the start time of a process in microseconds since epoch
+func processStartTime(pid int) (uint64, error) {
+	info, err := unix.SysctlKinfoProc("kern.proc.pid", pid)
+	if err != nil {
+		return 0, err
+	}
+	start := info.Proc.P_starttime
+	return uint64(start.Sec)*1e6 + uint64(start.Usec), nil
+}
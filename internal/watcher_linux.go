@@ -0,0 +1,61 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+// pidfdWatcher waits for a process which is not a child of runsyncapps using
+// a pidfd which becomes readable when the process exits, the pidfd always
+// refers to the same process even if its pid gets reused
+type pidfdWatcher struct {
+	pid int
+	fd  int
+}
+
+// newAdoptedWatcher returns a watcher for a process not started by runsyncapps
+func newAdoptedWatcher(pid int) (processWatcher, error) {
+	fd, err := unix.PidfdOpen(pid, 0)
+	if err != nil {
+		// pidfd is available since Linux 5.3
+		return newPollWatcher(pid), nil
+	}
+	return &pidfdWatcher{pid: pid, fd: fd}, nil
+}
+
+func (w *pidfdWatcher) Wait() processExit {
+	defer unix.Close(w.fd)
+
+	exit := processExit{pid: w.pid, exitCode: -1}
+	fds := []unix.PollFd{{Fd: int32(w.fd), Events: unix.POLLIN}}
+	for {
+		_, err := unix.Poll(fds, -1)
+		if errors.Is(err, unix.EINTR) {
+			continue
+		}
+		exit.err = err
+		return exit
+	}
+}
+
+// processStartTime returns the start time of a process in clock ticks since boot
+func processStartTime(pid int) (uint64, error) {
+	data, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return 0, err
+	}
+
+	// the process name can contain spaces and parenthesis, fields
+	// are counted after its closing parenthesis (starttime is the 22nd)
+	stat := string(data)
+	fields := strings.Fields(stat[strings.LastIndexByte(stat, ')')+1:])
+	if len(fields) < 20 {
+		return 0, errors.New("invalid stat content")
+	}
+	return strconv.ParseUint(fields[19], 10, 64)
+}
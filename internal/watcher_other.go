@@ -0,0 +1,13 @@
+//go:build !linux && !darwin && !windows
+
+package internal
+
+// newAdoptedWatcher returns a watcher for a process not started by runsyncapps
+func newAdoptedWatcher(pid int) (processWatcher, error) {
+	return newPollWatcher(pid), nil
+}
+
+// processStartTime returns the start time of a process
+func processStartTime(pid int) (uint64, error) {
+	return 0, errUnsupported
+}
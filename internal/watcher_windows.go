@@ -0,0 +1,49 @@
+package internal
+
+import (
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// handleWatcher waits for a process which is not a child of runsyncapps,
+// on Windows any process can be waited through its handle which also
+// prevents its pid from being reused while it is opened
+type handleWatcher struct {
+	process *os.Process
+}
+
+// newAdoptedWatcher returns a watcher for a process not started by runsyncapps
+func newAdoptedWatcher(pid int) (processWatcher, error) {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return nil, err
+	}
+	return &handleWatcher{process: process}, nil
+}
+
+func (w *handleWatcher) Wait() processExit {
+	exit := processExit{pid: w.process.Pid, exitCode: -1}
+	state, err := w.process.Wait()
+	if err != nil {
+		exit.err = err
+		return exit
+	}
+	exit.exitCode = state.ExitCode()
+	return exit
+}
+
+// processStartTime returns the creation time of a process in 100-nanosecond intervals
+func processStartTime(pid int) (uint64, error) {
+	h, err := windows.OpenProcess(windows.PROCESS_QUERY_LIMITED_INFORMATION, false, uint32(pid))
+	if err != nil {
+		return 0, err
+	}
+	defer windows.CloseHandle(h)
+
+	var creation, exit, kernel, user windows.Filetime
+	if err := windows.GetProcessTimes(h, &creation, &exit, &kernel, &user); err != nil {
+		return 0, err
+	}
+	return uint64(creation.HighDateTime)<<32 | uint64(creation.LowDateTime), nil
+}